@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	csvons "csvons/internal/csvons"
+)
+
+// defaultCacheDir is the result cache directory, relative to the ruler file.
+const defaultCacheDir = ".csvons-cache"
+
+// resultCache stores the outcome of passing rules on disk, keyed by a hash
+// of everything the outcome depends on: the csvons binary, the metadata, the
+// rule definition and the contents of the rule's input files (the source
+// file, destination files named by dst_file_stem and schema files). A rule
+// whose key has a stored entry is skipped and its stored warnings are
+// reported instead.
+//
+// Failing rules are never stored, so they are rerun and reported every time.
+// file_exists rules depend on a whole asset tree and are never cached.
+// Entries are not evicted; delete the directory to reclaim space.
+type resultCache struct {
+	dir      string
+	metadata *csvons.Metadata
+
+	mu     sync.Mutex
+	hashes map[string]string // File path → content hash, or "" if unreadable.
+
+	binaryOnce sync.Once
+	binary     string // Hash of the running executable, or "" if unreadable.
+}
+
+// resultCacheEntry is the on-disk form of a passing rule.
+type resultCacheEntry struct {
+	Stem     string                   `json:"stem"`
+	Rule     string                   `json:"rule"`
+	Warnings []csvons.ValidationError `json:"warnings"`
+}
+
+func newResultCache(dir string, metadata *csvons.Metadata) *resultCache {
+	return &resultCache{dir: dir, metadata: metadata, hashes: make(map[string]string)}
+}
+
+// wrap returns a task body that consults the cache before calling run and
+// stores run's warnings when it passes.
+func (c *resultCache) wrap(stem, ruleName string, rawRule json.RawMessage, run func(ctx context.Context) []csvons.ValidationError) func(ctx context.Context) []csvons.ValidationError {
+	return func(ctx context.Context) []csvons.ValidationError {
+		key, ok := c.key(stem, ruleName, rawRule)
+		if !ok {
+			return run(ctx)
+		}
+		if warnings, ok := c.load(key); ok {
+			log.Printf("rule [%s] of [%s] is unchanged; using cached result", ruleName, stem)
+			markCachedResult(ctx)
+			return warnings
+		}
+
+		// run panics when the rule fails or is cancelled, so only passing
+		// results are stored.
+		warnings := run(ctx)
+		c.store(key, resultCacheEntry{Stem: stem, Rule: ruleName, Warnings: warnings})
+		return warnings
+	}
+}
+
+// key hashes the inputs of a rule. It reports false when the rule cannot be
+// cached or one of its inputs cannot be read; the rule then simply runs.
+func (c *resultCache) key(stem, ruleName string, rawRule json.RawMessage) (string, bool) {
+	if ruleName == "file_exists" {
+		return "", false
+	}
+	binary := c.binaryHash()
+	if binary == "" {
+		return "", false
+	}
+	metadata, err := json.Marshal(c.metadata)
+	if err != nil {
+		return "", false
+	}
+	var rule any
+	if err := json.Unmarshal(rawRule, &rule); err != nil {
+		return "", false
+	}
+	// Re-marshal so whitespace and key order in the ruler file do not matter.
+	canonical, err := json.Marshal(rule)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	for _, part := range []string{binary, string(metadata), stem, ruleName, string(canonical)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	for _, path := range c.inputs(stem, rule) {
+		hash := c.fileHash(path)
+		if hash == "" {
+			return "", false
+		}
+		h.Write([]byte(path))
+		h.Write([]byte{0})
+		h.Write([]byte(hash))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// inputs returns the sorted paths of the files a rule reads: the source file
+// and every dst_file_stem and schema_file value found in the rule.
+func (c *resultCache) inputs(stem string, rule any) []string {
+	csvPath := func(stem string) string {
+		return filepath.Join(c.metadata.CSVFileFolder, stem+c.metadata.Extension)
+	}
+	paths := []string{csvPath(stem)}
+
+	var walk func(value any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			for key, child := range v {
+				name, isString := child.(string)
+				switch {
+				case key == "dst_file_stem" && isString:
+					paths = append(paths, csvPath(name))
+				case key == "schema_file" && isString && name != "":
+					if !filepath.IsAbs(name) {
+						name = filepath.Join(c.metadata.RulerDir, name)
+					}
+					paths = append(paths, name)
+				default:
+					walk(child)
+				}
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(rule)
+
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// fileHash returns the content hash of path, computing it once per run.
+func (c *resultCache) fileHash(path string) string {
+	c.mu.Lock()
+	hash, ok := c.hashes[path]
+	c.mu.Unlock()
+	if ok {
+		return hash
+	}
+
+	hash = hashFile(path)
+	c.mu.Lock()
+	c.hashes[path] = hash
+	c.mu.Unlock()
+	return hash
+}
+
+// binaryHash returns the content hash of the running executable, so results
+// of a different csvons build are never reused.
+func (c *resultCache) binaryHash() string {
+	c.binaryOnce.Do(func() {
+		path, err := os.Executable()
+		if err != nil {
+			log.Printf("result cache disabled: %v", err)
+			return
+		}
+		c.binary = hashFile(path)
+	})
+	return c.binary
+}
+
+// load returns the warnings of a stored entry.
+func (c *resultCache) load(key string) ([]csvons.ValidationError, bool) {
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var entry resultCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.Printf("ignoring corrupt cache entry %s: %v", key, err)
+		return nil, false
+	}
+	return entry.Warnings, true
+}
+
+// store writes an entry atomically, so concurrent runs sharing the directory
+// never read a partial entry. Errors are logged and otherwise ignored.
+func (c *resultCache) store(key string, entry resultCacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("error encoding cache entry %s: %v", key, err)
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		log.Printf("error creating cache dir %s: %v", c.dir, err)
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		log.Printf("error writing cache entry %s: %v", key, err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json"))
+	}
+	if err != nil {
+		log.Printf("error writing cache entry %s: %v", key, err)
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// hashFile returns the hex SHA-256 of a file's contents, or "" if it cannot
+// be read.
+func hashFile(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
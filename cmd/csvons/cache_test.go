@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	csvons "csvons/internal/csvons"
+)
+
+// countingRun returns a task body that counts its calls and reports one
+// warning.
+func countingRun(calls *int) func(context.Context) []csvons.ValidationError {
+	return func(context.Context) []csvons.ValidationError {
+		*calls++
+		return []csvons.ValidationError{{Rule: "outlier", Message: "suspicious", Severity: "warning"}}
+	}
+}
+
+func TestResultCacheRerunsOnlyChangedInputs(t *testing.T) {
+	dir := t.TempDir()
+	for stem, content := range map[string]string{"src": "ID\n1\n", "dst": "ID\n1\n", "other": "ID\n1\n"} {
+		if err := os.WriteFile(filepath.Join(dir, stem+".csv"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write csv failed: %v", err)
+		}
+	}
+	metadata := &csvons.Metadata{CSVFileFolder: dir, NameIndex: 0, DataIndex: 1, Extension: ".csv"}
+	cache := newResultCache(filepath.Join(dir, defaultCacheDir), metadata)
+	rule := json.RawMessage(`[{"dst_file_stem": "dst", "fields": [{"src": "ID", "dst": "ID"}]}]`)
+
+	calls := 0
+	run := cache.wrap("src", "exists", rule, countingRun(&calls))
+	if warnings := run(context.Background()); len(warnings) != 1 || calls != 1 {
+		t.Fatalf("first run: calls=%d warnings=%+v", calls, warnings)
+	}
+
+	// Unchanged inputs, including an unrelated file changing, reuse the result.
+	if err := os.WriteFile(filepath.Join(dir, "other.csv"), []byte("ID\n2\n"), 0o644); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+	cache = newResultCache(filepath.Join(dir, defaultCacheDir), metadata)
+	run = cache.wrap("src", "exists", rule, countingRun(&calls))
+	if warnings := run(context.Background()); len(warnings) != 1 || warnings[0].Message != "suspicious" || calls != 1 {
+		t.Fatalf("cached run: calls=%d warnings=%+v", calls, warnings)
+	}
+
+	// A changed destination file invalidates the entry.
+	if err := os.WriteFile(filepath.Join(dir, "dst.csv"), []byte("ID\n1\n2\n"), 0o644); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+	cache = newResultCache(filepath.Join(dir, defaultCacheDir), metadata)
+	cache.wrap("src", "exists", rule, countingRun(&calls))(context.Background())
+	if calls != 2 {
+		t.Fatalf("changed dst file did not rerun the rule: calls=%d", calls)
+	}
+
+	// A changed rule definition invalidates the entry as well.
+	cache.wrap("src", "exists", json.RawMessage(`[{"dst_file_stem": "other", "fields": [{"src": "ID", "dst": "ID"}]}]`), countingRun(&calls))(context.Background())
+	if calls != 3 {
+		t.Fatalf("changed rule did not rerun: calls=%d", calls)
+	}
+}
+
+func TestResultCacheSkipsFailuresAndFileExists(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "src.csv"), []byte("ID\n1\n"), 0o644); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+	metadata := &csvons.Metadata{CSVFileFolder: dir, NameIndex: 0, DataIndex: 1, Extension: ".csv"}
+	cache := newResultCache(filepath.Join(dir, defaultCacheDir), metadata)
+
+	calls := 0
+	fileExists := json.RawMessage(`[{"field": "ID"}]`)
+	cache.wrap("src", "file_exists", fileExists, countingRun(&calls))(context.Background())
+	cache.wrap("src", "file_exists", fileExists, countingRun(&calls))(context.Background())
+	if calls != 2 {
+		t.Fatalf("file_exists rule was cached: calls=%d", calls)
+	}
+
+	failing := func(context.Context) []csvons.ValidationError {
+		calls++
+		panic(csvons.ValidationError{Message: "failed", Code: 1})
+	}
+	unique := json.RawMessage(`{"fields": ["ID"]}`)
+	for range 2 {
+		if result := runTask(context.Background(), ruleTask{stem: "src", rule: "unique", run: cache.wrap("src", "unique", unique, failing)}); result.issue == nil {
+			t.Fatalf("expected failing result")
+		}
+	}
+	if calls != 4 {
+		t.Fatalf("failing rule was cached: calls=%d", calls)
+	}
+}
+
+func TestRunWithArgsNoCacheWritesNoCacheDir(t *testing.T) {
+	configPath := writeParallelFixture(t)
+	cacheDir := filepath.Join(filepath.Dir(configPath), defaultCacheDir)
+	reportPath := filepath.Join(filepath.Dir(configPath), "report.json")
+
+	runWithArgs([]string{"--format", "json", "--output", reportPath, "--no-cache", "--fail-fast=false", configPath})
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Fatalf("--no-cache created %s: %v", cacheDir, err)
+	}
+
+	// Every vtype rule and the unique rule of stem "b" pass and are cached.
+	runWithArgs([]string{"--format", "json", "--output", reportPath, "--fail-fast=false", configPath})
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatalf("read cache dir failed: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("unexpected cache entries: %v", entries)
+	}
+}
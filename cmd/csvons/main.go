@@ -2,34 +2,78 @@
 //
 // Usage:
 //
-//	csvons <ruler.json>
+//	csvons [--config-format json|jsonc|yaml|toml] [--stream] [--jobs N] [--fail-fast=false] [--timeout <duration>] [--no-cache] [--cache-dir <dir>] [--profile] <ruler.json>
 //
 // The program reads the specified ruler JSON file, parses the metadata
 // and constraint rules, then validates each referenced CSV file against its rules.
+// Rulers may also be written in YAML (.yaml, .yml), TOML (.toml) or JSON with
+// comments (.jsonc); the format follows the extension unless --config-format
+// names it.
+// With --stream, rules read source files record by record instead of loading
+// them whole, for files too large to hold in memory; structure and table still
+// load their file, with a warning.
+//
+// Rules run on a pool of --jobs workers, in the order stems and rules appear
+// in the ruler file. In fail-fast mode (the default) the first failing rule in
+// that order is reported and rules after it are cancelled; with
+// --fail-fast=false every failing rule is reported. Issues are sorted by file,
+// row and rule, so runs over the same data give identical reports.
+//
+// A run stops when --timeout elapses or on SIGINT/SIGTERM: running rules are
+// interrupted, the rules that did not complete are skipped and the report
+// gets a "validation cancelled" error (exit code 2).
+//
+// Passing rules are cached in .csvons-cache next to the ruler file; a rule
+// whose definition and input files are unchanged is not rerun. --no-cache
+// disables the cache.
+//
+// With --profile, the JSON report profiles every rule: wall time, rows
+// scanned, values checked, cache hits and, with --jobs 1, peak heap. The
+// profile is also written as a table to stderr. Without it, reports over the
+// same data are byte-identical.
 //
 // Supported constraints:
 //   - exists: values in a column must exist in another CSV file's column
-//   - unique: values in a column must be unique across all rows
+//   - unique: values in a column must be unique across all rows, within a row, or within a group
 //   - vtype: values must conform to a specified type and optional range
+//   - structure: rows must match the header's shape, with no blank rows
+//   - table: row counts and column aggregates must fall within bounds
+//   - sorted: values must appear in ascending or descending order
+//   - sequence: integer values must form a contiguous sequence
+//   - array_shape: array cells must have the expected element count and arity
+//   - map_keys: key sets of key=value map cells must satisfy key constraints
+//   - file_exists: values must name existing files in a local asset tree
+//   - i18n: every key must have complete, placeholder-consistent translations
+//   - outlier: numeric outliers are reported as warnings without failing
+//   - graph: row references must form acyclic, rooted and reachable graphs
+//   - distribution: key:weight cells must have positive weights, a target sum and unique keys
+//   - lookup_compare: values must compare correctly with the joined row of another file
 package main
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
 	csvons "csvons/internal/csvons"
 )
 
 type validationSummary struct {
-	FilesChecked int   `json:"files_checked"`
-	Passed       int   `json:"passed"`
-	Failed       int   `json:"failed"`
-	DurationMS   int64 `json:"duration_ms"`
+	FilesChecked int `json:"files_checked"`
+	Passed       int `json:"passed"`
+	Failed       int `json:"failed"`
 }
 
 type validationIssue struct {
@@ -43,32 +87,70 @@ type validationIssue struct {
 }
 
 type validationReport struct {
-	SchemaVersion string            `json:"schema_version,omitempty"`
-	Summary       validationSummary `json:"summary"`
-	Issues        []validationIssue `json:"issues"`
+	SchemaVersion string             `json:"schema_version,omitempty"`
+	Summary       validationSummary  `json:"summary"`
+	Profile       *validationProfile `json:"profile,omitempty"` // Set with --profile.
+	Issues        []validationIssue  `json:"issues"`
 }
 
-const reportSchemaVersion = "csvons.validation_report.v1"
+const reportSchemaVersion = "csvons.validation_report.v2"
 
 func main() {
 	os.Exit(run())
 }
 
+// run validates with the command-line arguments and cancels the run on the
+// first SIGINT or SIGTERM; a second signal terminates the process.
 func run() int {
-	return runWithArgs(os.Args[1:])
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			cancel(fmt.Errorf("received %v", sig))
+		case <-ctx.Done():
+		}
+	}()
+
+	return runWithContext(ctx, os.Args[1:])
 }
 
-func runWithArgs(args []string) (code int) {
+func runWithArgs(args []string) int {
+	return runWithContext(context.Background(), args)
+}
+
+// runWithContext validates with args until ctx is cancelled.
+func runWithContext(ctx context.Context, args []string) (code int) {
 	var format string
 	var outputPath string
-	var rules map[string]json.RawMessage
+	var configFormat string
+	var streaming bool
+	var jobs int
+	var failFast bool
+	var timeout time.Duration
+	var noCache bool
+	var cacheDir string
+	var profile bool
 
 	flags := flag.NewFlagSet("csvons", flag.ContinueOnError)
 	flags.SetOutput(os.Stderr)
 	flags.StringVar(&format, "format", "text", "output format: text or json")
 	flags.StringVar(&outputPath, "output", "", "optional output file path")
+	flags.StringVar(&configFormat, "config-format", "", "ruler file format: "+strings.Join(csvons.ConfigFormats, ", ")+" (default from the file extension)")
+	flags.BoolVar(&streaming, "stream", false, "stream source files instead of loading them whole (all rules but structure and table)")
+	flags.IntVar(&jobs, "jobs", 1, "number of rules validated concurrently; 0 uses one per CPU")
+	flags.BoolVar(&failFast, "fail-fast", true, "stop at the first failing rule; false reports every failing rule")
+	flags.DurationVar(&timeout, "timeout", 0, "cancel the run after this duration (e.g. 30s, 5m); 0 means no limit")
+	flags.BoolVar(&noCache, "no-cache", false, "rerun every rule instead of reusing cached results of unchanged rules")
+	flags.StringVar(&cacheDir, "cache-dir", "", "result cache directory (default "+defaultCacheDir+" next to the ruler file)")
+	flags.BoolVar(&profile, "profile", false, "profile each rule's time and memory in the JSON report and as a table on stderr")
 	flags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [--format text|json] [--output <path>] <ruler.json>\n", flags.Name())
+		fmt.Fprintf(os.Stderr, "Usage: %s [--format text|json] [--output <path>] [--config-format json|jsonc|yaml|toml] [--stream] [--jobs N] [--fail-fast=false] [--timeout <duration>] [--no-cache] [--cache-dir <dir>] [--profile] <ruler.json>\n", flags.Name())
 		fmt.Fprintf(os.Stderr, "\nValidate CSV files against constraint rules defined in a JSON configuration file.\n")
 		flags.PrintDefaults()
 	}
@@ -84,27 +166,25 @@ func runWithArgs(args []string) (code int) {
 		fmt.Fprintf(os.Stderr, "invalid --format value %q; expected text or json\n", format)
 		return 2
 	}
+	if configFormat != "" && !slices.Contains(csvons.ConfigFormats, configFormat) {
+		fmt.Fprintf(os.Stderr, "invalid --config-format value %q; expected one of %s\n", configFormat, strings.Join(csvons.ConfigFormats, ", "))
+		return 2
+	}
+	if jobs < 0 {
+		fmt.Fprintf(os.Stderr, "invalid --jobs value %d; expected 0 or more\n", jobs)
+		return 2
+	}
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	if timeout < 0 {
+		fmt.Fprintf(os.Stderr, "invalid --timeout value %v; expected 0 or more\n", timeout)
+		return 2
+	}
 
 	configFileName := flags.Arg(0)
 	startAt := time.Now()
-	defer func() {
-		recovered := recover()
-		if recovered == nil {
-			return
-		}
-
-		issue, issueCode := validationIssueFromRecovered(recovered)
-		_ = emitOutput(format, outputPath, validationReport{
-			Summary: validationSummary{
-				FilesChecked: len(rules),
-				Passed:       0,
-				Failed:       1,
-				DurationMS:   time.Since(startAt).Milliseconds(),
-			},
-			Issues: []validationIssue{issue},
-		})
-		code = issueCode
-	}()
+	defer reportPanic(format, outputPath, &code)
 
 	metadataFile := func(stem string, metadata *csvons.Metadata) string {
 		if metadata == nil {
@@ -113,8 +193,7 @@ func runWithArgs(args []string) (code int) {
 		return stem + metadata.Extension
 	}
 
-	var metadata *csvons.Metadata
-	rules, metadata = csvons.ReadConfigFile(configFileName)
+	rules, metadata := csvons.ReadConfigFileFormat(configFileName, configFormat)
 	if rules == nil || metadata == nil {
 		_ = emitOutput(format, outputPath, validationReport{
 			Summary: validationSummary{},
@@ -125,10 +204,29 @@ func runWithArgs(args []string) (code int) {
 		})
 		return 2
 	}
+	if streaming {
+		metadata.Streaming = true
+	}
+	var cache *resultCache
+	if !noCache {
+		if cacheDir == "" {
+			cacheDir = filepath.Join(metadata.RulerDir, defaultCacheDir)
+		}
+		cache = newResultCache(cacheDir, metadata)
+	}
 
-	for stem, rawRules := range rules {
+	// Decode every rule before running any, so configuration errors are
+	// reported without partial validation. Tasks follow the order of stems
+	// and rules in the ruler file.
+	var tasks []ruleTask
+	for _, stem := range metadata.Stems {
 		rulers := map[string]json.RawMessage{}
-		if err := json.Unmarshal(rawRules, &rulers); err != nil {
+		err := json.Unmarshal(rules[stem], &rulers)
+		var ruleNames []string
+		if err == nil {
+			ruleNames, err = csvons.ObjectKeys(rules[stem])
+		}
+		if err != nil {
 			_ = emitOutput(format, outputPath, validationReport{
 				Summary: validationSummary{},
 				Issues: []validationIssue{{
@@ -140,61 +238,17 @@ func runWithArgs(args []string) (code int) {
 			return 2
 		}
 
-		for ruleName, rawRule := range rulers {
-			switch ruleName {
-			case "exists":
-				var exists []csvons.Exists
-				if err := json.Unmarshal(rawRule, &exists); err != nil {
-					_ = emitOutput(format, outputPath, validationReport{
-						Summary: validationSummary{},
-						Issues: []validationIssue{{
-							File:     metadataFile(stem, metadata),
-							Rule:     ruleName,
-							Message:  fmt.Sprintf("error unmarshalling exists: error=%v", err),
-							Severity: "error",
-						}},
-					})
-					return 2
-				}
-				csvons.ExistsTest(stem, exists, metadata)
-
-			case "unique":
-				var unique csvons.Unique
-				if err := json.Unmarshal(rawRule, &unique); err != nil {
-					_ = emitOutput(format, outputPath, validationReport{
-						Summary: validationSummary{},
-						Issues: []validationIssue{{
-							File:     metadataFile(stem, metadata),
-							Rule:     ruleName,
-							Message:  fmt.Sprintf("error unmarshalling unique: error=%v", err),
-							Severity: "error",
-						}},
-					})
-					return 2
-				}
-				csvons.UniqueTest(stem, &unique, metadata)
-
-			case "vtype":
-				var vtype []csvons.VType
-				if err := json.Unmarshal(rawRule, &vtype); err != nil {
-					_ = emitOutput(format, outputPath, validationReport{
-						Summary: validationSummary{},
-						Issues: []validationIssue{{
-							File:     metadataFile(stem, metadata),
-							Rule:     ruleName,
-							Message:  fmt.Sprintf("error unmarshalling vtype: error=%v", err),
-							Severity: "error",
-						}},
-					})
-					return 2
-				}
-				csvons.VTypeTest(stem, vtype, metadata)
-
-			default:
+		for _, ruleName := range ruleNames {
+			file := metadataFile(stem, metadata)
+			body, err := decodeRule(stem, ruleName, rulers[ruleName])
+			if err != nil {
+				return emitRuleError(format, outputPath, file, ruleName, err)
+			}
+			if body == nil {
 				_ = emitOutput(format, outputPath, validationReport{
 					Summary: validationSummary{},
 					Issues: []validationIssue{{
-						File:     metadataFile(stem, metadata),
+						File:     file,
 						Rule:     ruleName,
 						Message:  fmt.Sprintf("unknown key %s", ruleName),
 						Severity: "error",
@@ -202,39 +256,162 @@ func runWithArgs(args []string) (code int) {
 				})
 				return 2
 			}
+			run := func(ctx context.Context) []csvons.ValidationError {
+				return body(metadata.WithContext(ctx))
+			}
+			if cache != nil {
+				run = cache.wrap(stem, ruleName, rulers[ruleName], run)
+			}
+			tasks = append(tasks, ruleTask{stem: stem, rule: ruleName, run: run})
+		}
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %v", timeout))
+		defer cancel()
+	}
+	var monitor *heapMonitor
+	if profile {
+		monitor = startHeapMonitor(heapSampleInterval)
+		defer monitor.close()
+	}
+	results := runTasks(ctx, tasks, jobs, failFast, monitor)
+
+	// Collect issues in task order. Stems with a failing rule count as
+	// failed; stems whose rules were cancelled count as neither.
+	var runProfile *validationProfile
+	if profile {
+		runProfile = newValidationProfile(tasks, results, time.Since(startAt), monitor.runPeak())
+	}
+	issues := []validationIssue{}
+	failedStems := map[string]bool{}
+	skippedStems := map[string]bool{}
+	for i, result := range results {
+		if result.skipped {
+			skippedStems[tasks[i].stem] = true
+			continue
+		}
+		for _, warning := range result.warnings {
+			issues = append(issues, issueFromValidationError(warning))
+		}
+		if result.issue != nil {
+			issues = append(issues, *result.issue)
+			failedStems[tasks[i].stem] = true
+			code = max(code, result.code)
+		}
+	}
+	sortIssues(issues)
+	if err := context.Cause(ctx); err != nil {
+		issues = append(issues, validationIssue{
+			Message:  fmt.Sprintf("validation cancelled: %v", err),
+			Severity: "error",
+		})
+		code = 2
+	}
+	passed := 0
+	for stem := range rules {
+		if !failedStems[stem] && !skippedStems[stem] {
+			passed++
 		}
 	}
 
-	durationMs := time.Since(startAt).Milliseconds()
 	report := validationReport{
 		Summary: validationSummary{
 			FilesChecked: len(rules),
-			Passed:       len(rules),
-			Failed:       0,
-			DurationMS:   durationMs,
+			Passed:       passed,
+			Failed:       len(failedStems),
 		},
-		Issues: []validationIssue{},
+		Profile: runProfile,
+		Issues:  issues,
 	}
 
 	if err := emitOutput(format, outputPath, report); err != nil {
 		log.Printf("error writing output: %v", err)
 		return 2
 	}
-	return 0
+	if runProfile != nil {
+		if err := writeProfileTable(os.Stderr, runProfile); err != nil {
+			log.Printf("error writing profile: %v", err)
+		}
+	}
+	return code
+}
+
+// reportPanic turns a panic outside the rule tasks, such as an internal
+// error while reading the configuration, into a one-issue report and sets
+// *code to its exit code (2 unless it is a validation failure). It must be
+// deferred directly.
+func reportPanic(format, outputPath string, code *int) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	issue, issueCode := validationIssueFromRecovered(recovered)
+	_ = emitOutput(format, outputPath, validationReport{
+		Summary: validationSummary{},
+		Issues:  []validationIssue{issue},
+	})
+	*code = issueCode
+}
+
+// sortIssues orders issues by file, row and rule so that reports over the
+// same data are byte-identical. Issues without a row come first within their
+// file; ties keep task order.
+func sortIssues(issues []validationIssue) {
+	slices.SortStableFunc(issues, func(a, b validationIssue) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(issueRow(a), issueRow(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Rule, b.Rule)
+	})
+}
+
+// issueRow returns the issue's row, or 0 when it has none.
+func issueRow(issue validationIssue) int {
+	if issue.Row == nil {
+		return 0
+	}
+	return *issue.Row
+}
+
+// emitRuleError reports a rule definition that cannot be unmarshalled and
+// returns the configuration error exit code.
+func emitRuleError(format, outputPath, file, ruleName string, err error) int {
+	_ = emitOutput(format, outputPath, validationReport{
+		Summary: validationSummary{},
+		Issues: []validationIssue{{
+			File:     file,
+			Rule:     ruleName,
+			Message:  fmt.Sprintf("error unmarshalling %s: error=%v", ruleName, err),
+			Severity: "error",
+		}},
+	})
+	return 2
+}
+
+// issueFromValidationError converts a structured validation error or warning
+// into a report issue.
+func issueFromValidationError(v csvons.ValidationError) validationIssue {
+	return validationIssue{
+		File:     v.File,
+		Rule:     v.Rule,
+		Field:    v.Field,
+		Row:      v.Row,
+		Value:    v.Value,
+		Message:  v.Error(),
+		Severity: v.Severity,
+	}
 }
 
 func validationIssueFromRecovered(recovered any) (validationIssue, int) {
 	switch v := recovered.(type) {
 	case csvons.ValidationError:
-		return validationIssue{
-			File:     v.File,
-			Rule:     v.Rule,
-			Field:    v.Field,
-			Row:      v.Row,
-			Value:    v.Value,
-			Message:  v.Error(),
-			Severity: v.Severity,
-		}, v.ExitCode()
+		return issueFromValidationError(v), v.ExitCode()
 	case error:
 		return validationIssue{
 			Message:  v.Error(),
@@ -262,16 +439,17 @@ func emitOutput(format, outputPath string, report validationReport) error {
 		out = append(data, '\n')
 	default:
 		var b bytes.Buffer
-		if len(report.Issues) > 0 {
-			for _, issue := range report.Issues {
-				fmt.Fprintf(&b, "[%s] %s\n", issue.Severity, issue.Message)
-			}
-		} else {
-			fmt.Fprintf(&b, "Validation succeeded: files_checked=%d passed=%d failed=%d duration_ms=%d\n",
+		failed := false
+		for _, issue := range report.Issues {
+			fmt.Fprintf(&b, "[%s] %s\n", issue.Severity, issue.Message)
+			failed = failed || issue.Severity != "warning"
+		}
+		// Warnings alone do not fail validation, so the success line still follows them.
+		if !failed {
+			fmt.Fprintf(&b, "Validation succeeded: files_checked=%d passed=%d failed=%d\n",
 				report.Summary.FilesChecked,
 				report.Summary.Passed,
 				report.Summary.Failed,
-				report.Summary.DurationMS,
 			)
 		}
 		out = b.Bytes()
@@ -1,17 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 )
 
 func TestEmitOutputJSONToStdout(t *testing.T) {
 	report := validationReport{
-		Summary: validationSummary{FilesChecked: 2, Passed: 2, Failed: 0, DurationMS: 15},
+		Summary: validationSummary{FilesChecked: 2, Passed: 2, Failed: 0},
 		Issues:  []validationIssue{},
 	}
 
@@ -49,7 +54,7 @@ func TestEmitOutputJSONToStdout(t *testing.T) {
 
 func TestEmitOutputTextToFile(t *testing.T) {
 	report := validationReport{
-		Summary: validationSummary{FilesChecked: 1, Passed: 1, Failed: 0, DurationMS: 8},
+		Summary: validationSummary{FilesChecked: 1, Passed: 1, Failed: 0},
 		Issues:  []validationIssue{},
 	}
 
@@ -64,7 +69,7 @@ func TestEmitOutputTextToFile(t *testing.T) {
 		t.Fatalf("read output file failed: %v", err)
 	}
 
-	want := "Validation succeeded: files_checked=1 passed=1 failed=0 duration_ms=8\n"
+	want := "Validation succeeded: files_checked=1 passed=1 failed=0\n"
 	if string(data) != want {
 		t.Fatalf("unexpected text output\nwant: %q\n got: %q", want, string(data))
 	}
@@ -205,6 +210,316 @@ func TestRunWithArgsJSONRuntimeFailureUsesExitCodeTwo(t *testing.T) {
 	}
 }
 
+func TestRunWithArgsJSONOutlierWarningsDoNotFail(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "monsters.csv")
+	if err := os.WriteFile(csvPath, []byte("Damage\n500\n510\n50000\n"), 0o644); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+
+	configPath := filepath.Join(dir, "ruler.json")
+	config := map[string]any{
+		"monsters": map[string]any{
+			"outlier": []map[string]any{
+				{"field": "Damage", "method": "ratio"},
+			},
+		},
+		"csvons_metadata": map[string]any{
+			"csv_file_folder": dir,
+			"name_index":      0,
+			"data_index":      1,
+			"extension":       ".csv",
+		},
+	}
+	writeJSONFile(t, configPath, config)
+
+	reportPath := filepath.Join(dir, "report.json")
+	code := runWithArgs([]string{"--format", "json", "--output", reportPath, configPath})
+	if code != 0 {
+		t.Fatalf("unexpected exit code: got %d want 0", code)
+	}
+
+	report := readReportFile(t, reportPath)
+	if report.Summary.Passed != 1 || report.Summary.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", report.Summary)
+	}
+	if len(report.Issues) != 1 {
+		t.Fatalf("unexpected issue count: %d", len(report.Issues))
+	}
+	issue := report.Issues[0]
+	if issue.Severity != "warning" || issue.Rule != "outlier" || issue.Row == nil || *issue.Row != 4 {
+		t.Fatalf("unexpected issue: %+v", issue)
+	}
+}
+
+func TestRunWithArgsStreamReportsSameIssue(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "users.csv")
+	if err := os.WriteFile(csvPath, []byte("Username,Age\nalpha,1\nbeta,x\n"), 0o644); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+
+	configPath := filepath.Join(dir, "ruler.json")
+	config := map[string]any{
+		"users": map[string]any{
+			"vtype": []map[string]any{
+				{"field": "Age", "type": "int"},
+			},
+		},
+		"csvons_metadata": map[string]any{
+			"csv_file_folder": dir,
+			"name_index":      0,
+			"data_index":      1,
+			"extension":       ".csv",
+		},
+	}
+	writeJSONFile(t, configPath, config)
+
+	reportPath := filepath.Join(dir, "report.json")
+	code := runWithArgs([]string{"--format", "json", "--output", reportPath, "--stream", configPath})
+	if code != 1 {
+		t.Fatalf("unexpected exit code: got %d want 1", code)
+	}
+
+	report := readReportFile(t, reportPath)
+	if len(report.Issues) != 1 {
+		t.Fatalf("unexpected issue count: %d", len(report.Issues))
+	}
+	issue := report.Issues[0]
+	if issue.Rule != "vtype" || issue.Row == nil || *issue.Row != 3 || issue.Value != "x" {
+		t.Fatalf("unexpected issue: %+v", issue)
+	}
+}
+
+// writeParallelFixture writes three stems: "a" and "c" with a duplicated
+// value, "b" without, and returns the ruler path.
+func writeParallelFixture(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	config := map[string]any{
+		"csvons_metadata": map[string]any{
+			"csv_file_folder": dir,
+			"name_index":      0,
+			"data_index":      1,
+			"extension":       ".csv",
+		},
+	}
+	for stem, content := range map[string]string{"a": "ID\n1\n1\n", "b": "ID\n1\n2\n", "c": "ID\n3\n3\n"} {
+		if err := os.WriteFile(filepath.Join(dir, stem+".csv"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write csv failed: %v", err)
+		}
+		config[stem] = map[string]any{
+			"unique": map[string]any{"fields": []string{"ID"}},
+			"vtype":  []map[string]any{{"field": "ID", "type": "int"}},
+		}
+	}
+
+	configPath := filepath.Join(dir, "ruler.json")
+	writeJSONFile(t, configPath, config)
+	return configPath
+}
+
+func TestRunWithArgsJobsFailFastIsDeterministic(t *testing.T) {
+	configPath := writeParallelFixture(t)
+	reportPath := filepath.Join(filepath.Dir(configPath), "report.json")
+
+	for range 20 {
+		code := runWithArgs([]string{"--format", "json", "--output", reportPath, "--jobs", "4", configPath})
+		if code != 1 {
+			t.Fatalf("unexpected exit code: got %d want 1", code)
+		}
+
+		report := readReportFile(t, reportPath)
+		if len(report.Issues) != 1 {
+			t.Fatalf("unexpected issue count: %d", len(report.Issues))
+		}
+		if issue := report.Issues[0]; issue.File != "a.csv" || issue.Rule != "unique" {
+			t.Fatalf("unexpected issue: %+v", issue)
+		}
+		if report.Summary.Failed != 1 || report.Summary.Passed != 0 {
+			t.Fatalf("unexpected summary: %+v", report.Summary)
+		}
+	}
+}
+
+func TestRunWithArgsJobsWithoutFailFastReportsEveryFailure(t *testing.T) {
+	configPath := writeParallelFixture(t)
+	reportPath := filepath.Join(filepath.Dir(configPath), "report.json")
+
+	code := runWithArgs([]string{"--format", "json", "--output", reportPath, "--jobs", "0", "--fail-fast=false", configPath})
+	if code != 1 {
+		t.Fatalf("unexpected exit code: got %d want 1", code)
+	}
+
+	report := readReportFile(t, reportPath)
+	if len(report.Issues) != 2 {
+		t.Fatalf("unexpected issue count: %d", len(report.Issues))
+	}
+	if report.Issues[0].File != "a.csv" || report.Issues[1].File != "c.csv" {
+		t.Fatalf("unexpected issue order: %+v", report.Issues)
+	}
+	if report.Summary.FilesChecked != 3 || report.Summary.Failed != 2 || report.Summary.Passed != 1 {
+		t.Fatalf("unexpected summary: %+v", report.Summary)
+	}
+}
+
+func TestRunWithArgsTimeoutReportsCancelledRun(t *testing.T) {
+	configPath := writeParallelFixture(t)
+	reportPath := filepath.Join(filepath.Dir(configPath), "report.json")
+
+	code := runWithArgs([]string{"--format", "json", "--output", reportPath, "--no-cache", "--timeout", "1ns", configPath})
+	if code != 2 {
+		t.Fatalf("unexpected exit code: got %d want 2", code)
+	}
+
+	report := readReportFile(t, reportPath)
+	if len(report.Issues) != 1 || report.Issues[0].Message != "validation cancelled: timed out after 1ns" {
+		t.Fatalf("unexpected issues: %+v", report.Issues)
+	}
+	if report.Summary.FilesChecked != 3 || report.Summary.Failed != 0 || report.Summary.Passed != 0 {
+		t.Fatalf("unexpected summary: %+v", report.Summary)
+	}
+
+	if code := runWithArgs([]string{"--timeout", "-1s", configPath}); code != 2 {
+		t.Fatalf("negative timeout accepted: exit code %d", code)
+	}
+}
+
+func TestRunWithContextReportsCancelCause(t *testing.T) {
+	configPath := writeParallelFixture(t)
+	reportPath := filepath.Join(filepath.Dir(configPath), "report.json")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(fmt.Errorf("received interrupt"))
+	if code := runWithContext(ctx, []string{"--format", "json", "--output", reportPath, "--no-cache", configPath}); code != 2 {
+		t.Fatalf("unexpected exit code: got %d want 2", code)
+	}
+	report := readReportFile(t, reportPath)
+	if len(report.Issues) != 1 || report.Issues[0].Message != "validation cancelled: received interrupt" {
+		t.Fatalf("unexpected issues: %+v", report.Issues)
+	}
+}
+
+func TestRunWithArgsFollowsRulerOrderAndSortsIssues(t *testing.T) {
+	dir := t.TempDir()
+	for stem, content := range map[string]string{"a": "ID\n1\n1\n", "c": "ID\nx\n3\n3\n"} {
+		if err := os.WriteFile(filepath.Join(dir, stem+".csv"), []byte(content), 0o644); err != nil {
+			t.Fatalf("write csv failed: %v", err)
+		}
+	}
+
+	// Stem "c" comes first in the ruler, and its vtype rule before unique.
+	configPath := filepath.Join(dir, "ruler.json")
+	config := `{
+		"c": {"vtype": [{"field": "ID", "type": "int"}], "unique": {"fields": ["ID"]}},
+		"a": {"unique": {"fields": ["ID"]}},
+		"csvons_metadata": {"csv_file_folder": ` + strconv.Quote(dir) + `, "name_index": 0, "data_index": 1, "extension": ".csv"}
+	}`
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+
+	reportPath := filepath.Join(dir, "report.json")
+	if code := runWithArgs([]string{"--format", "json", "--output", reportPath, configPath}); code != 1 {
+		t.Fatalf("unexpected exit code: got %d want 1", code)
+	}
+	report := readReportFile(t, reportPath)
+	if len(report.Issues) != 1 || report.Issues[0].File != "c.csv" || report.Issues[0].Rule != "vtype" {
+		t.Fatalf("unexpected fail-fast issues: %+v", report.Issues)
+	}
+
+	if code := runWithArgs([]string{"--format", "json", "--output", reportPath, "--fail-fast=false", configPath}); code != 1 {
+		t.Fatalf("unexpected exit code: got %d want 1", code)
+	}
+	report = readReportFile(t, reportPath)
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, fmt.Sprintf("%s:%d:%s", issue.File, *issue.Row, issue.Rule))
+	}
+	if expected := []string{"a.csv:3:unique", "c.csv:2:vtype", "c.csv:4:unique"}; !slices.Equal(got, expected) {
+		t.Fatalf("unexpected issue order: %q, expected %q", got, expected)
+	}
+
+	// Reports are byte-identical between runs, whatever the pool size.
+	for _, format := range []string{"text", "json"} {
+		path := filepath.Join(dir, "report."+format)
+		if code := runWithArgs([]string{"--format", format, "--output", path, "--fail-fast=false", configPath}); code != 1 {
+			t.Fatalf("unexpected exit code: got %d want 1", code)
+		}
+		expected, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read report failed: %v", err)
+		}
+		for range 5 {
+			if code := runWithArgs([]string{"--format", format, "--output", path, "--fail-fast=false", "--jobs", "4", configPath}); code != 1 {
+				t.Fatalf("unexpected exit code: got %d want 1", code)
+			}
+			if again, err := os.ReadFile(path); err != nil || !bytes.Equal(again, expected) {
+				t.Fatalf("%s report changed between runs: %q, expected %q (err=%v)", format, again, expected, err)
+			}
+		}
+	}
+}
+
+func TestRunWithArgsReadsYAMLRuler(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "users.csv")
+	if err := os.WriteFile(csvPath, []byte("Username\nalpha\nalpha\n"), 0o644); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+
+	// The ruler is YAML inside a .txt file, so only --config-format finds it.
+	configPath := filepath.Join(dir, "ruler.txt")
+	config := "# Usernames identify players.\nusers:\n  unique:\n    fields: [Username]\n" +
+		"csvons_metadata:\n  csv_file_folder: " + strconv.Quote(dir) + "\n  name_index: 0\n  data_index: 1\n  extension: .csv\n"
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+
+	reportPath := filepath.Join(dir, "report.json")
+	if code := runWithArgs([]string{"--format", "json", "--output", reportPath, configPath}); code != 2 {
+		t.Fatalf("unexpected exit code without --config-format: got %d want 2", code)
+	}
+	code := runWithArgs([]string{"--format", "json", "--output", reportPath, "--config-format", "yaml", configPath})
+	if code != 1 {
+		t.Fatalf("unexpected exit code: got %d want 1", code)
+	}
+	report := readReportFile(t, reportPath)
+	if len(report.Issues) != 1 || report.Issues[0].Rule != "unique" {
+		t.Fatalf("unexpected issues: %+v", report.Issues)
+	}
+
+	if code := runWithArgs([]string{"--config-format", "xml", configPath}); code != 2 {
+		t.Fatalf("unexpected exit code for unknown --config-format: got %d want 2", code)
+	}
+}
+
+func TestEmitOutputTextWithWarnings(t *testing.T) {
+	report := validationReport{
+		Summary: validationSummary{FilesChecked: 1, Passed: 1, Failed: 0},
+		Issues: []validationIssue{
+			{Severity: "warning", Message: "value is an outlier"},
+		},
+	}
+
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "report.txt")
+	if err := emitOutput("text", outPath, report); err != nil {
+		t.Fatalf("emitOutput(text,file) error: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read output file failed: %v", err)
+	}
+
+	want := "[warning] value is an outlier\nValidation succeeded: files_checked=1 passed=1 failed=0\n"
+	if string(data) != want {
+		t.Fatalf("unexpected text output\nwant: %q\n got: %q", want, string(data))
+	}
+}
+
 func writeJSONFile(t *testing.T, path string, value any) {
 	t.Helper()
 
@@ -231,3 +546,22 @@ func readReportFile(t *testing.T, path string) validationReport {
 	}
 	return report
 }
+
+func TestReportPanicEmitsExitCodeTwoReport(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	code := func() (code int) {
+		defer reportPanic("json", reportPath, &code)
+		panic("unexpected state")
+	}()
+	if code != 2 {
+		t.Fatalf("unexpected exit code: got %d want 2", code)
+	}
+
+	report := readReportFile(t, reportPath)
+	if len(report.Issues) != 1 {
+		t.Fatalf("unexpected issue count: %d", len(report.Issues))
+	}
+	if issue := report.Issues[0]; issue.Message != "unexpected state" || issue.Severity != "error" {
+		t.Fatalf("unexpected issue: %#v", issue)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// validationProfile reports where a run spent its time and memory.
+// DurationMS is the wall time of the whole run, from reading the ruler file
+// to the last rule. PeakHeapBytes is the largest live heap sampled while
+// any rule ran.
+type validationProfile struct {
+	DurationMS    float64       `json:"duration_ms"`
+	PeakHeapBytes uint64        `json:"peak_heap_bytes"`
+	Rules         []ruleProfile `json:"rules"`
+}
+
+// ruleProfile is the cost of one rule of one stem. Rows, values and cache
+// hits are the csvons.RuleStats counters. PeakHeapBytes is the largest live
+// heap sampled while the rule ran. Samples are process-wide, so it is only
+// set when rules run one at a time; with --jobs > 1 it would include the
+// memory of rules running alongside.
+type ruleProfile struct {
+	Stem          string  `json:"stem"`
+	Rule          string  `json:"rule"`
+	Status        string  `json:"status"` // passed, failed, cached or skipped.
+	DurationMS    float64 `json:"duration_ms"`
+	Rows          int64   `json:"rows"`
+	Values        int64   `json:"values"`
+	CacheHits     int64   `json:"cache_hits"`
+	PeakHeapBytes uint64  `json:"peak_heap_bytes,omitempty"`
+}
+
+// newValidationProfile builds the profile of a run from its tasks' results,
+// in task order.
+func newValidationProfile(tasks []ruleTask, results []taskResult, duration time.Duration, peakHeap uint64) *validationProfile {
+	profile := &validationProfile{
+		DurationMS:    float64(duration.Microseconds()) / 1000,
+		PeakHeapBytes: peakHeap,
+		Rules:         make([]ruleProfile, len(results)),
+	}
+	for i, result := range results {
+		rule := result.profile
+		rule.Stem, rule.Rule = tasks[i].stem, tasks[i].rule
+		switch {
+		case result.skipped:
+			rule.Status = "skipped"
+		case result.issue != nil:
+			rule.Status = "failed"
+		case result.cached:
+			rule.Status = "cached"
+		default:
+			rule.Status = "passed"
+		}
+		profile.Rules[i] = rule
+	}
+	return profile
+}
+
+// heapMetric is the runtime metric sampled for peak memory: bytes of live
+// and not yet swept heap objects.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// heapSampleInterval is how often heapMonitor samples the heap.
+const heapSampleInterval = 10 * time.Millisecond
+
+// heapMonitor samples the heap in the background and tracks the peak seen
+// while any task runs and while each task runs.
+type heapMonitor struct {
+	mu      sync.Mutex
+	samples []metrics.Sample
+	peak    uint64         // Peak heap bytes while any task runs.
+	peaks   map[int]uint64 // Task index → peak heap bytes while it runs.
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func startHeapMonitor(interval time.Duration) *heapMonitor {
+	m := &heapMonitor{
+		samples: []metrics.Sample{{Name: heapMetric}},
+		peaks:   make(map[int]uint64),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.sample()
+			}
+		}
+	}()
+	return m
+}
+
+// sample reads the heap size and raises the run's peak and the peak of
+// every running task.
+func (m *heapMonitor) sample() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.peaks) == 0 {
+		return
+	}
+	metrics.Read(m.samples)
+	if m.samples[0].Value.Kind() != metrics.KindUint64 {
+		return
+	}
+	heap := m.samples[0].Value.Uint64()
+	m.peak = max(m.peak, heap)
+	for i, peak := range m.peaks {
+		m.peaks[i] = max(peak, heap)
+	}
+}
+
+// begin starts tracking task i.
+func (m *heapMonitor) begin(i int) {
+	m.mu.Lock()
+	m.peaks[i] = 0
+	m.mu.Unlock()
+	m.sample()
+}
+
+// end stops tracking task i and returns its peak.
+func (m *heapMonitor) end(i int) uint64 {
+	m.sample()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	peak := m.peaks[i]
+	delete(m.peaks, i)
+	return peak
+}
+
+// runPeak returns the peak heap seen while any task ran.
+func (m *heapMonitor) runPeak() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peak
+}
+
+// close stops the sampling goroutine.
+func (m *heapMonitor) close() {
+	close(m.stop)
+	<-m.done
+}
+
+type cachedResultKey struct{}
+
+// withCachedResultFlag returns a copy of ctx in which markCachedResult sets
+// *cached.
+func withCachedResultFlag(ctx context.Context, cached *bool) context.Context {
+	return context.WithValue(ctx, cachedResultKey{}, cached)
+}
+
+// markCachedResult records that a task's result came from the result cache.
+func markCachedResult(ctx context.Context) {
+	if cached, ok := ctx.Value(cachedResultKey{}).(*bool); ok {
+		*cached = true
+	}
+}
+
+// writeProfileTable writes the profile as an aligned text table, one line
+// per rule in task order, followed by the run's wall time and peak heap.
+// Rules without a peak heap show "-".
+func writeProfileTable(w io.Writer, profile *validationProfile) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEM\tRULE\tSTATUS\tTIME_MS\tROWS\tVALUES\tCACHE_HITS\tPEAK_HEAP_KB")
+	for _, rule := range profile.Rules {
+		peakHeap := "-"
+		if rule.PeakHeapBytes > 0 {
+			peakHeap = strconv.FormatUint(rule.PeakHeapBytes/1024, 10)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.3f\t%d\t%d\t%d\t%s\n",
+			rule.Stem, rule.Rule, rule.Status, rule.DurationMS, rule.Rows, rule.Values, rule.CacheHits, peakHeap)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "Total time: %.3f ms\nPeak heap: %d KiB\n", profile.DurationMS, profile.PeakHeapBytes/1024)
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunWithArgsReportsProfile(t *testing.T) {
+	configPath := writeParallelFixture(t)
+	reportPath := filepath.Join(filepath.Dir(configPath), "report.json")
+
+	summarize := func(report validationReport) []string {
+		t.Helper()
+		if report.Profile == nil {
+			t.Fatalf("report has no profile")
+		}
+		var got []string
+		for _, rule := range report.Profile.Rules {
+			got = append(got, fmt.Sprintf("%s/%s:%s:%d:%d:%d", rule.Stem, rule.Rule, rule.Status, rule.Rows, rule.Values, rule.CacheHits))
+			if (rule.Status == "passed" || rule.Status == "failed") && rule.PeakHeapBytes == 0 {
+				t.Errorf("%s/%s has no peak heap", rule.Stem, rule.Rule)
+			}
+		}
+		return got
+	}
+
+	// Stems a and c repeat a value: vtype skips it as a cache hit and unique
+	// fails on it.
+	runWithArgs([]string{"--format", "json", "--output", reportPath, "--profile", "--fail-fast=false", configPath})
+	report := readReportFile(t, reportPath)
+	expected := []string{
+		"a/unique:failed:2:2:0", "a/vtype:passed:2:2:1",
+		"b/unique:passed:2:2:0", "b/vtype:passed:2:2:0",
+		"c/unique:failed:2:2:0", "c/vtype:passed:2:2:1",
+	}
+	if got := summarize(report); strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Fatalf("unexpected profile:\n%q\nexpected\n%q", got, expected)
+	}
+	if report.Profile.PeakHeapBytes == 0 {
+		t.Fatalf("run has no peak heap")
+	}
+
+	// Rerunning reuses the passing results without reading any rows.
+	runWithArgs([]string{"--format", "json", "--output", reportPath, "--profile", "--fail-fast=false", configPath})
+	expected = []string{
+		"a/unique:failed:2:2:0", "a/vtype:cached:0:0:0",
+		"b/unique:cached:0:0:0", "b/vtype:cached:0:0:0",
+		"c/unique:failed:2:2:0", "c/vtype:cached:0:0:0",
+	}
+	if got := summarize(readReportFile(t, reportPath)); strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Fatalf("unexpected cached profile:\n%q\nexpected\n%q", got, expected)
+	}
+
+	// In fail-fast mode the rules after the first failure are skipped.
+	runWithArgs([]string{"--format", "json", "--output", reportPath, "--profile", "--no-cache", configPath})
+	got := summarize(readReportFile(t, reportPath))
+	if got[0] != "a/unique:failed:2:2:0" || got[1] != "a/vtype:skipped:0:0:0" {
+		t.Fatalf("unexpected fail-fast profile: %q", got)
+	}
+
+	// With several jobs only the run's peak heap is reported.
+	runWithArgs([]string{"--format", "json", "--output", reportPath, "--profile", "--no-cache", "--fail-fast=false", "--jobs", "4", configPath})
+	report = readReportFile(t, reportPath)
+	if report.Profile == nil || report.Profile.PeakHeapBytes == 0 {
+		t.Fatalf("run has no peak heap: %+v", report.Profile)
+	}
+	for _, rule := range report.Profile.Rules {
+		if rule.PeakHeapBytes != 0 {
+			t.Fatalf("%s/%s has a peak heap with --jobs 4", rule.Stem, rule.Rule)
+		}
+	}
+
+	// Without --profile the report has no profile.
+	runWithArgs([]string{"--format", "json", "--output", reportPath, "--no-cache", configPath})
+	if report := readReportFile(t, reportPath); report.Profile != nil {
+		t.Fatalf("unexpected profile without --profile: %+v", report.Profile)
+	}
+}
+
+func TestWriteProfileTable(t *testing.T) {
+	var b bytes.Buffer
+	err := writeProfileTable(&b, &validationProfile{
+		DurationMS:    20.25,
+		PeakHeapBytes: 4096,
+		Rules: []ruleProfile{
+			{Stem: "items", Rule: "exists", Status: "passed", DurationMS: 12.5, Rows: 1000, Values: 1200, CacheHits: 200, PeakHeapBytes: 2048},
+			{Stem: "quests", Rule: "vtype", Status: "skipped"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("writeProfileTable failed: %v", err)
+	}
+
+	expected := "" +
+		"STEM    RULE    STATUS   TIME_MS  ROWS  VALUES  CACHE_HITS  PEAK_HEAP_KB\n" +
+		"items   exists  passed   12.500   1000  1200    200         2\n" +
+		"quests  vtype   skipped  0.000    0     0       0           -\n" +
+		"Total time: 20.250 ms\n" +
+		"Peak heap: 4 KiB\n"
+	if b.String() != expected {
+		t.Fatalf("unexpected table:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+}
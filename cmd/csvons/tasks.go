@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	csvons "csvons/internal/csvons"
+)
+
+// ruleTask is one decoded rule of one stem. run returns the warnings the
+// rule reports and panics with a ValidationError when validation fails or
+// ctx is cancelled.
+type ruleTask struct {
+	stem string
+	rule string
+	run  func(ctx context.Context) []csvons.ValidationError
+}
+
+// taskResult is the outcome of a ruleTask.
+type taskResult struct {
+	warnings  []csvons.ValidationError
+	issue     *validationIssue // Set when the rule failed.
+	code      int              // Exit code of issue.
+	skipped   bool             // The task was cancelled before or while running.
+	cancelled bool             // The rule aborted because ctx was cancelled.
+	profile   ruleProfile      // Cost of the run; Stem, Rule and Status are left to the caller.
+	cached    bool             // The result came from the result cache.
+}
+
+// decodeRule unmarshals a rule definition into a task body that validates
+// stem with the given metadata. It returns a nil function for unknown rule
+// names.
+func decodeRule(stem, ruleName string, rawRule json.RawMessage) (func(metadata *csvons.Metadata) []csvons.ValidationError, error) {
+	switch ruleName {
+	case "exists":
+		var exists []csvons.Exists
+		if err := json.Unmarshal(rawRule, &exists); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.ExistsTest(stem, exists, metadata)
+			return nil
+		}, nil
+
+	case "unique":
+		var unique csvons.Unique
+		if err := json.Unmarshal(rawRule, &unique); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.UniqueTest(stem, &unique, metadata)
+			return nil
+		}, nil
+
+	case "vtype":
+		var vtype []csvons.VType
+		if err := json.Unmarshal(rawRule, &vtype); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.VTypeTest(stem, vtype, metadata)
+			return nil
+		}, nil
+
+	case "structure":
+		var structure csvons.Structure
+		if err := json.Unmarshal(rawRule, &structure); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.StructureTest(stem, &structure, metadata)
+			return nil
+		}, nil
+
+	case "table":
+		var table csvons.TableRule
+		if err := json.Unmarshal(rawRule, &table); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.TableTest(stem, &table, metadata)
+			return nil
+		}, nil
+
+	case "sorted":
+		var sorted []csvons.Sorted
+		if err := json.Unmarshal(rawRule, &sorted); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.SortedTest(stem, sorted, metadata)
+			return nil
+		}, nil
+
+	case "sequence":
+		var sequence []csvons.Sequence
+		if err := json.Unmarshal(rawRule, &sequence); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.SequenceTest(stem, sequence, metadata)
+			return nil
+		}, nil
+
+	case "array_shape":
+		var shapes []csvons.ArrayShape
+		if err := json.Unmarshal(rawRule, &shapes); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.ArrayShapeTest(stem, shapes, metadata)
+			return nil
+		}, nil
+
+	case "map_keys":
+		var mapKeys []csvons.MapKeys
+		if err := json.Unmarshal(rawRule, &mapKeys); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.MapKeysTest(stem, mapKeys, metadata)
+			return nil
+		}, nil
+
+	case "file_exists":
+		var fileExists []csvons.FileExists
+		if err := json.Unmarshal(rawRule, &fileExists); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.FileExistsTest(stem, fileExists, metadata)
+			return nil
+		}, nil
+
+	case "i18n":
+		var i18n []csvons.I18n
+		if err := json.Unmarshal(rawRule, &i18n); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.I18nTest(stem, i18n, metadata)
+			return nil
+		}, nil
+
+	case "outlier":
+		var outlier []csvons.Outlier
+		if err := json.Unmarshal(rawRule, &outlier); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			return csvons.OutlierTest(stem, outlier, metadata)
+		}, nil
+
+	case "graph":
+		var graph []csvons.Graph
+		if err := json.Unmarshal(rawRule, &graph); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.GraphTest(stem, graph, metadata)
+			return nil
+		}, nil
+
+	case "distribution":
+		var distribution []csvons.Distribution
+		if err := json.Unmarshal(rawRule, &distribution); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.DistributionTest(stem, distribution, metadata)
+			return nil
+		}, nil
+
+	case "lookup_compare":
+		var lookups []csvons.LookupCompare
+		if err := json.Unmarshal(rawRule, &lookups); err != nil {
+			return nil, err
+		}
+		return func(metadata *csvons.Metadata) []csvons.ValidationError {
+			csvons.LookupCompareTest(stem, lookups, metadata)
+			return nil
+		}, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// runTasks runs tasks on a pool of jobs workers and returns their results in
+// task order.
+//
+// In fail-fast mode a failure cancels every task after it: tasks that have
+// not started are skipped and running ones are interrupted through their
+// context. Tasks before it still run, so the first failing task in task
+// order always runs; results after it are marked skipped whether or not
+// they ran, which keeps the report independent of scheduling.
+//
+// Once ctx is done, tasks that have not started are skipped and running ones
+// are interrupted. Interrupted tasks are marked skipped as well.
+//
+// With a non-nil monitor, the heap is sampled while tasks run. With one job
+// each result's profile also gets the task's peak heap; with more, samples
+// cannot be attributed to a single task.
+func runTasks(ctx context.Context, tasks []ruleTask, jobs int, failFast bool, monitor *heapMonitor) []taskResult {
+	results := make([]taskResult, len(tasks))
+
+	var mu sync.Mutex
+	firstFailed := len(tasks)                         // Index of the first failed task seen so far.
+	cancels := make([]context.CancelFunc, len(tasks)) // Cancels the tasks that started.
+	start := func(i int) (context.Context, context.CancelFunc, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil || failFast && i > firstFailed {
+			return nil, nil, false
+		}
+		taskCtx, cancel := context.WithCancel(ctx)
+		cancels[i] = cancel
+		return taskCtx, cancel, true
+	}
+	failed := func(i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		firstFailed = min(firstFailed, i)
+		if !failFast {
+			return
+		}
+		for j := i + 1; j < len(cancels); j++ {
+			if cancels[j] != nil {
+				cancels[j]()
+			}
+		}
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, max(len(tasks), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				taskCtx, cancel, ok := start(i)
+				if !ok {
+					results[i].skipped = true
+					continue
+				}
+				if monitor != nil {
+					monitor.begin(i)
+				}
+				result := runTask(taskCtx, tasks[i])
+				if monitor != nil {
+					if peak := monitor.end(i); jobs == 1 {
+						result.profile.PeakHeapBytes = peak
+					}
+				}
+				cancel()
+				switch {
+				case result.cancelled:
+					results[i] = taskResult{skipped: true}
+				case result.issue != nil:
+					results[i] = result
+					failed(i)
+				default:
+					results[i] = result
+				}
+			}
+		}()
+	}
+	for i := range tasks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if failFast {
+		for i := firstFailed + 1; i < len(results); i++ {
+			results[i] = taskResult{skipped: true}
+		}
+	}
+	return results
+}
+
+// runTask runs one task, recovering a validation panic into its result,
+// and measures its time and csvons.RuleStats counters.
+func runTask(ctx context.Context, task ruleTask) (result taskResult) {
+	var stats csvons.RuleStats
+	ctx = csvons.WithRuleStats(ctx, &stats)
+	ctx = withCachedResultFlag(ctx, &result.cached)
+	startAt := time.Now()
+	defer func() {
+		result.profile.DurationMS = float64(time.Since(startAt).Microseconds()) / 1000
+		result.profile.Rows = stats.Rows.Load()
+		result.profile.Values = stats.Values.Load()
+		result.profile.CacheHits = stats.CacheHits.Load()
+	}()
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		if err, ok := recovered.(error); ok && errors.Is(err, csvons.ErrCancelled) {
+			result.cancelled = true
+			return
+		}
+		issue, code := validationIssueFromRecovered(recovered)
+		result.issue = &issue
+		result.code = code
+	}()
+
+	result.warnings = task.run(ctx)
+	return result
+}
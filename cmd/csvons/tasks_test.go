@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	csvons "csvons/internal/csvons"
+)
+
+// TestRunTasksInterruptsRunningTasksAfterFailure verifies that in fail-fast
+// mode a failure cancels the context of tasks after it that are running.
+func TestRunTasksInterruptsRunningTasksAfterFailure(t *testing.T) {
+	started := make(chan struct{})
+	tasks := []ruleTask{
+		{stem: "a", rule: "unique", run: func(context.Context) []csvons.ValidationError {
+			<-started
+			panic(csvons.ValidationError{Message: "failed", Code: 1})
+		}},
+		{stem: "b", rule: "unique", run: func(ctx context.Context) []csvons.ValidationError {
+			close(started)
+			<-ctx.Done()
+			panic(fmt.Errorf("b: %w", csvons.ErrCancelled))
+		}},
+	}
+
+	results := runTasks(context.Background(), tasks, 2, true, nil)
+	if results[0].issue == nil || results[0].code != 1 {
+		t.Fatalf("expected the first task to fail: %+v", results[0])
+	}
+	if !results[1].skipped || results[1].issue != nil {
+		t.Fatalf("expected the interrupted task to be skipped: %+v", results[1])
+	}
+}
+
+// TestRunTasksSkipsTasksAfterCancel verifies that no task starts once the
+// run context is done.
+func TestRunTasksSkipsTasksAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	tasks := []ruleTask{
+		{stem: "a", rule: "unique", run: countingRun(&calls)},
+		{stem: "b", rule: "unique", run: countingRun(&calls)},
+	}
+	for i, result := range runTasks(ctx, tasks, 1, false, nil) {
+		if !result.skipped {
+			t.Fatalf("task %d was not skipped: %+v", i, result)
+		}
+	}
+	if calls != 0 {
+		t.Fatalf("cancelled run started %d tasks", calls)
+	}
+}
+
+// TestRunTasksKeepsResultsFinishedAfterCancel verifies that tasks which
+// complete or fail on their own after the run is cancelled keep their
+// results; only a task that aborted because of the cancellation is skipped.
+func TestRunTasksKeepsResultsFinishedAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ready := make(chan struct{})
+	tasks := []ruleTask{
+		{stem: "a", rule: "unique", run: func(ctx context.Context) []csvons.ValidationError {
+			<-ready
+			<-ready
+			cancel()
+			<-ctx.Done()
+			panic(csvons.ValidationError{Message: "failed", Code: 1})
+		}},
+		{stem: "a", rule: "outlier", run: func(ctx context.Context) []csvons.ValidationError {
+			ready <- struct{}{}
+			<-ctx.Done()
+			return []csvons.ValidationError{{Message: "suspicious", Severity: "warning"}}
+		}},
+		{stem: "a", rule: "exists", run: func(ctx context.Context) []csvons.ValidationError {
+			ready <- struct{}{}
+			<-ctx.Done()
+			panic(fmt.Errorf("exists: %w", csvons.ErrCancelled))
+		}},
+	}
+
+	results := runTasks(ctx, tasks, len(tasks), false, nil)
+	if results[0].skipped || results[0].issue == nil || results[0].code != 1 {
+		t.Fatalf("expected the failure to be kept: %+v", results[0])
+	}
+	if results[1].skipped || len(results[1].warnings) != 1 {
+		t.Fatalf("expected the completed task to keep its warnings: %+v", results[1])
+	}
+	if !results[2].skipped || results[2].issue != nil {
+		t.Fatalf("expected the cancelled task to be skipped: %+v", results[2])
+	}
+}
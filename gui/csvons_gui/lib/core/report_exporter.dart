@@ -11,7 +11,6 @@ class ReportExporter {
         'files_checked': report.summary.filesChecked,
         'passed': report.summary.passed,
         'failed': report.summary.failed,
-        'duration_ms': report.summary.durationMs,
       },
       'issues': report.issues
           .map(
@@ -39,7 +38,6 @@ class ReportExporter {
       ..writeln('- Files checked: ${report.summary.filesChecked}')
       ..writeln('- Passed: ${report.summary.passed}')
       ..writeln('- Failed: ${report.summary.failed}')
-      ..writeln('- Duration: ${report.summary.durationMs} ms')
       ..writeln();
 
     if (report.issues.isEmpty) {
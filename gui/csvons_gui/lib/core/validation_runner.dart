@@ -16,22 +16,31 @@ class ValidationRunner {
 
   final String binaryPath;
   final ProcessStarter _processStarter;
+  Process? _process;
 
   Future<ValidationResult> run({
     required String rulerPath,
     bool jsonFormat = true,
+    Duration? timeout,
   }) async {
     final executable = resolveBinaryPath(binaryPath);
     final args = <String>[
       if (jsonFormat) ...['--format', 'json'],
+      if (timeout != null) ...['--timeout', '${timeout.inMilliseconds}ms'],
       rulerPath,
     ];
 
     final proc = await _processStarter(executable, args);
+    _process = proc;
     final outFuture = proc.stdout.transform(utf8.decoder).join();
     final errFuture = proc.stderr.transform(utf8.decoder).join();
 
-    final exitCode = await proc.exitCode;
+    final int exitCode;
+    try {
+      exitCode = await proc.exitCode;
+    } finally {
+      _process = null;
+    }
     final out = await outFuture;
     final err = await errFuture;
 
@@ -54,6 +63,18 @@ class ValidationRunner {
     );
   }
 
+  /// Asks the running validation to stop. csvons then reports the rules it
+  /// completed with a "validation cancelled" error. Windows has no SIGINT,
+  /// so the process is terminated there without a report.
+  /// Returns false when no run is in progress.
+  bool cancel() {
+    final proc = _process;
+    if (proc == null) return false;
+    return proc.kill(
+      Platform.isWindows ? ProcessSignal.sigterm : ProcessSignal.sigint,
+    );
+  }
+
   static String defaultBinaryPath() {
     for (final candidate in _existingCandidatePaths()) {
       return candidate;
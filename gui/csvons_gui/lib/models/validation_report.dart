@@ -34,13 +34,11 @@ class ValidationSummary {
   final int filesChecked;
   final int passed;
   final int failed;
-  final int durationMs;
 
   ValidationSummary({
     required this.filesChecked,
     required this.passed,
     required this.failed,
-    required this.durationMs,
   });
 
   factory ValidationSummary.fromJson(Map<String, dynamic>? json) {
@@ -49,7 +47,6 @@ class ValidationSummary {
       filesChecked: (j['files_checked'] as num?)?.toInt() ?? 0,
       passed: (j['passed'] as num?)?.toInt() ?? 0,
       failed: (j['failed'] as num?)?.toInt() ?? 0,
-      durationMs: (j['duration_ms'] as num?)?.toInt() ?? 0,
     );
   }
 }
@@ -28,6 +28,7 @@ class _HomePageState extends State<HomePage> {
   );
 
   bool _running = false;
+  ValidationRunner? _runner;
   bool _exporting = false;
   ValidationResult? _result;
   String? _error;
@@ -88,7 +89,10 @@ class _HomePageState extends State<HomePage> {
     try {
       final file = await openFile(
         acceptedTypeGroups: const <XTypeGroup>[
-          XTypeGroup(label: 'JSON', extensions: <String>['json']),
+          XTypeGroup(
+            label: 'Ruler',
+            extensions: <String>['json', 'jsonc', 'yaml', 'yml', 'toml'],
+          ),
         ],
       );
       if (file == null) return;
@@ -161,6 +165,7 @@ class _HomePageState extends State<HomePage> {
         exportPath: _exportPathController.text.trim(),
       );
       final runner = ValidationRunner(binaryPath: binary);
+      _runner = runner;
       final result = await runner.run(rulerPath: ruler);
       final latestState = await _stateStore.load();
       setState(() {
@@ -174,6 +179,7 @@ class _HomePageState extends State<HomePage> {
         _error = e.toString();
       });
     } finally {
+      _runner = null;
       if (mounted) {
         setState(() {
           _running = false;
@@ -182,6 +188,10 @@ class _HomePageState extends State<HomePage> {
     }
   }
 
+  void _cancelRun() {
+    _runner?.cancel();
+  }
+
   Future<void> _exportReport({required bool markdown}) async {
     final report = _result?.report;
     if (report == null) {
@@ -276,7 +286,7 @@ class _HomePageState extends State<HomePage> {
             TextField(
               controller: _rulerController,
               decoration: InputDecoration(
-                labelText: 'ruler.json absolute path',
+                labelText: 'Ruler file absolute path',
                 suffixIcon: IconButton(
                   tooltip: 'Browse ruler',
                   icon: const Icon(Icons.folder_open),
@@ -297,6 +307,14 @@ class _HomePageState extends State<HomePage> {
                   icon: const Icon(Icons.play_arrow),
                   label: Text(_running ? 'Running...' : 'Run Validation'),
                 ),
+                if (_running) ...[
+                  const SizedBox(width: 8),
+                  OutlinedButton.icon(
+                    onPressed: _cancelRun,
+                    icon: const Icon(Icons.stop),
+                    label: const Text('Cancel'),
+                  ),
+                ],
                 const SizedBox(width: 8),
                 TextButton.icon(
                   onPressed: _running ? null : _clearRecents,
@@ -12,7 +12,6 @@ void main() {
         filesChecked: 2,
         passed: 1,
         failed: 1,
-        durationMs: 12,
       ),
       issues: [
         ValidationIssue(
@@ -23,7 +23,6 @@ void main() {
     expect(report.summary.filesChecked, 2);
     expect(report.summary.passed, 0);
     expect(report.summary.failed, 0);
-    expect(report.summary.durationMs, 0);
     expect(report.issues, hasLength(1));
     expect(report.issues.first.severity, 'error');
     expect(report.issues.first.row, 11);
@@ -10,14 +10,20 @@ class _FakeProcess implements Process {
     required this.stdoutText,
     required this.stderrText,
     required this.code,
+    this.exited,
   });
 
   final String stdoutText;
   final String stderrText;
   final int code;
+  final Future<void>? exited;
+  final signals = <ProcessSignal>[];
 
   @override
-  Future<int> get exitCode async => code;
+  Future<int> get exitCode async {
+    await exited;
+    return code;
+  }
 
   @override
   int get pid => 42;
@@ -32,7 +38,10 @@ class _FakeProcess implements Process {
   Stream<List<int>> get stdout => Stream<List<int>>.value(utf8.encode(stdoutText));
 
   @override
-  bool kill([ProcessSignal signal = ProcessSignal.sigterm]) => true;
+  bool kill([ProcessSignal signal = ProcessSignal.sigterm]) {
+    signals.add(signal);
+    return true;
+  }
 }
 
 void main() {
@@ -98,6 +107,54 @@ void main() {
     expect(result.report, isNull);
   });
 
+  test('passes the timeout in milliseconds', () async {
+    late List<String> capturedArgs;
+    final runner = ValidationRunner(
+      binaryPath: '/tmp/csvons',
+      processStarter: (_, arguments) async {
+        capturedArgs = arguments;
+        return _FakeProcess(code: 0, stdoutText: '', stderrText: '');
+      },
+    );
+
+    await runner.run(
+      rulerPath: '/tmp/ruler.json',
+      timeout: const Duration(seconds: 30),
+    );
+
+    expect(
+      capturedArgs,
+      ['--format', 'json', '--timeout', '30000ms', '/tmp/ruler.json'],
+    );
+  });
+
+  test('cancel signals the running process only', () async {
+    final exited = Completer<void>();
+    final proc = _FakeProcess(
+      code: 2,
+      stdoutText: '',
+      stderrText: '',
+      exited: exited.future,
+    );
+    final runner = ValidationRunner(
+      binaryPath: '/tmp/csvons',
+      processStarter: (_, __) async => proc,
+    );
+
+    expect(runner.cancel(), isFalse);
+    final result = runner.run(rulerPath: '/tmp/ruler.json');
+    await Future<void>.delayed(Duration.zero);
+
+    expect(runner.cancel(), isTrue);
+    expect(proc.signals, [
+      Platform.isWindows ? ProcessSignal.sigterm : ProcessSignal.sigint,
+    ]);
+
+    exited.complete();
+    expect((await result).exitCode, 2);
+    expect(runner.cancel(), isFalse);
+  });
+
   test('resolves an empty binary path to the bundled platform location', () async {
     final originalCurrentDir = Directory.current.path;
     final tempDir = await Directory.systemTemp.createTemp('csvons_gui_runner');
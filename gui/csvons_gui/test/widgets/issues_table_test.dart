@@ -11,7 +11,6 @@ void main() {
         filesChecked: 1,
         passed: 0,
         failed: 1,
-        durationMs: 12,
       ),
       issues: <ValidationIssue>[
         ValidationIssue(
@@ -154,7 +153,6 @@ void main() {
         filesChecked: 1,
         passed: 0,
         failed: 1,
-        durationMs: 1,
       ),
       issues: <ValidationIssue>[
         ValidationIssue(
@@ -181,7 +179,6 @@ void main() {
         filesChecked: 1,
         passed: 1,
         failed: 0,
-        durationMs: 1,
       ),
       issues: const <ValidationIssue>[],
     );
@@ -216,7 +213,6 @@ void main() {
         filesChecked: 1,
         passed: 0,
         failed: 1,
-        durationMs: 1,
       ),
       issues: <ValidationIssue>[
         ValidationIssue(
@@ -0,0 +1,52 @@
+package csvons
+
+import (
+	"context"
+	"errors"
+)
+
+// cancelCheckInterval is the number of records read between checks of the
+// metadata's context, so the check stays cheap on large files.
+const cancelCheckInterval = 1024
+
+// ErrCancelled is wrapped by the ValidationError a rule aborts with when its
+// metadata's context is done. Test for it with errors.Is.
+var ErrCancelled = errors.New("validation cancelled")
+
+// WithContext returns a shallow copy of metadata whose rules abort once ctx
+// is done. The copy shares the caches of metadata.
+//
+// Cancellation is checked before a file is read, while it is parsed, while
+// field expressions iterate records, while streamed files are read and
+// during the graph walks, outlier scoring and external sorts that follow.
+// A cancelled rule aborts via failRuntime with a "validation cancelled"
+// message and an error that wraps ErrCancelled.
+func (m *Metadata) WithContext(ctx context.Context) *Metadata {
+	if ctx == nil {
+		panic("csvons: nil context")
+	}
+	copied := *m
+	copied.ctx = ctx
+	return &copied
+}
+
+// Context returns the context set by WithContext, or context.Background.
+func (m *Metadata) Context() context.Context {
+	if m == nil || m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// checkCancelled aborts like failRuntime, with an error wrapping
+// ErrCancelled, when the metadata's context is done.
+func checkCancelled(ctx ValidationContext, metadata *Metadata) {
+	if metadata == nil || metadata.ctx == nil {
+		return
+	}
+	if err := context.Cause(metadata.ctx); err != nil {
+		cancelled := ctx.validationError(2, "validation cancelled: %v", err)
+		cancelled.cause = ErrCancelled
+		panic(cancelled)
+	}
+}
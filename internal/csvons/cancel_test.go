@@ -0,0 +1,113 @@
+package csvons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestCancelledContextAbortsRules verifies that rules run with a cancelled
+// context fail with a runtime error wrapping ErrCancelled, in memory and in streaming mode, and
+// that WithContext leaves the original metadata untouched.
+func TestCancelledContextAbortsRules(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "items", "ID\n1\n2\n")
+	metadata := testMetadata(dir)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(fmt.Errorf("stopped by test"))
+	for _, streaming := range []bool{false, true} {
+		metadata.Streaming = streaming
+		got := expectValidationError(t, func() {
+			UniqueTest("items", &Unique{Fields: []string{"ID"}}, metadata.WithContext(ctx))
+		})
+		if got.ExitCode() != 2 || got.Message != "validation cancelled: stopped by test" || !errors.Is(got, ErrCancelled) {
+			t.Fatalf("streaming=%v: unexpected error: %#v", streaming, got)
+		}
+	}
+
+	if metadata.Context() != context.Background() {
+		t.Fatalf("WithContext modified the original metadata")
+	}
+	UniqueTest("items", &Unique{Fields: []string{"ID"}}, metadata)
+}
+
+// TestCancelledContextStopsIteration verifies that a field expression stops
+// within cancelCheckInterval records of its context being cancelled.
+func TestCancelledContextStopsIteration(t *testing.T) {
+	var content strings.Builder
+	content.WriteString("ID\n")
+	for i := range 10 * cancelCheckInterval {
+		fmt.Fprintf(&content, "%d\n", i)
+	}
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "items", content.String())
+
+	for _, streaming := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		metadata := testMetadata(dir)
+		metadata.Streaming = streaming
+		metadata = metadata.WithContext(ctx)
+		source := requiredRecordSource(ValidationContext{}, "items", metadata)
+
+		read := 0
+		got := expectValidationError(t, func() {
+			for range source.occurrences(GenerateFieldExpr(metadata, "ID"), "ID", ValidationContext{}) {
+				read++
+				if read == 10 {
+					cancel()
+				}
+			}
+		})
+		if !strings.Contains(got.Message, "context canceled") || read > cancelCheckInterval+1 {
+			t.Fatalf("streaming=%v: read %d values, error %#v", streaming, read, got)
+		}
+	}
+}
+
+// TestCancelledParseIsNotCached verifies that a table parse aborted by one
+// rule's cancellation is parsed again for the next caller.
+func TestCancelledParseIsNotCached(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "items", "ID\n1\n2\n")
+	metadata := testMetadata(dir)
+	metadata.Tables = NewTableCache()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got := expectValidationError(t, func() {
+		metadata.Tables.Load("items", metadata.WithContext(ctx), 0)
+	})
+	if got.ExitCode() != 2 || !strings.Contains(got.Message, "validation cancelled") {
+		t.Fatalf("unexpected error: %#v", got)
+	}
+
+	if table := metadata.Tables.Load("items", metadata, 0); table == nil || len(table.Records) != 3 {
+		t.Fatalf("cancelled parse was cached: %#v", table)
+	}
+}
+
+// TestCancelledContextStopsGraphWalks verifies that graph walks check the
+// context as they visit nodes.
+func TestCancelledContextStopsGraphWalks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	graph := &rowGraph{
+		nodes:    []string{"a", "b"},
+		children: map[string][]string{"a": {"b"}},
+		metadata: testMetadata(t.TempDir()).WithContext(ctx),
+	}
+
+	for name, walk := range map[string]func(){
+		"findCycle": func() { graph.findCycle() },
+		"depths":    func() { graph.depths([]string{"a"}) },
+		"reachable": func() { graph.reachable([]string{"a"}) },
+	} {
+		got := expectValidationError(t, walk)
+		if !strings.Contains(got.Message, "context canceled") {
+			t.Fatalf("%s: unexpected error: %#v", name, got)
+		}
+	}
+}
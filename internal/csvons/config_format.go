@@ -0,0 +1,241 @@
+package csvons
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ConfigFormats lists the ruler file formats ReadConfigFileFormat accepts.
+//
+//   - json: plain JSON
+//   - jsonc: JSON with // and /* */ comments and trailing commas
+//   - yaml: a YAML subset (see parseYAMLConfig)
+//   - toml: a TOML subset (see parseTOMLConfig)
+//
+// Every format is converted to JSON with its keys in document order, so all
+// of them map onto the same ConstrainsConfig model.
+var ConfigFormats = []string{"json", "jsonc", "yaml", "toml"}
+
+// ConfigFormatFromName returns the ruler format implied by a file name's
+// extension: .jsonc, .yaml/.yml and .toml select their format, anything
+// else is json.
+func ConfigFormatFromName(configFileName string) string {
+	switch strings.ToLower(filepath.Ext(configFileName)) {
+	case ".jsonc":
+		return "jsonc"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	default:
+		return "json"
+	}
+}
+
+// ConfigToJSON converts a ruler file in the given format to JSON, keeping
+// object keys in document order.
+func ConfigToJSON(data []byte, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return data, nil
+	case "jsonc":
+		return stripJSONComments(data)
+	case "yaml":
+		value, err := parseYAMLConfig(data)
+		if err != nil {
+			return nil, err
+		}
+		return marshalConfigValue(value)
+	case "toml":
+		value, err := parseTOMLConfig(data)
+		if err != nil {
+			return nil, err
+		}
+		return marshalConfigValue(value)
+	default:
+		return nil, fmt.Errorf("unknown config format %q; expected one of %s", format, strings.Join(ConfigFormats, ", "))
+	}
+}
+
+// configSyntaxError is a syntax error in a YAML or TOML ruler file.
+type configSyntaxError struct {
+	line    int
+	message string
+}
+
+func (e *configSyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.message)
+}
+
+// failConfigSyntax aborts parsing with a configSyntaxError; the parser's
+// entry point turns it into an error with recoverConfigSyntax.
+func failConfigSyntax(line int, format string, args ...any) {
+	panic(&configSyntaxError{line: line, message: fmt.Sprintf(format, args...)})
+}
+
+// recoverConfigSyntax stores a configSyntaxError panic in *err. Other
+// panics are re-raised.
+func recoverConfigSyntax(err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	syntaxErr, ok := recovered.(*configSyntaxError)
+	if !ok {
+		panic(recovered)
+	}
+	*err = syntaxErr
+}
+
+// configObject is a decoded YAML mapping or TOML table that keeps its keys
+// in document order.
+type configObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func newConfigObject() *configObject {
+	return &configObject{values: make(map[string]any)}
+}
+
+// get returns the value of key, if set.
+func (o *configObject) get(key string) (any, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// set adds or replaces key, keeping the position of an existing key.
+func (o *configObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// configNumber is a number kept in its JSON text form, so integers of any
+// size and floats round-trip unchanged.
+type configNumber string
+
+// jsonNumberPattern matches the JSON number grammar.
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// marshalConfigValue encodes a decoded configuration value as JSON. Values
+// are *configObject, []any, string, configNumber, bool or nil.
+func marshalConfigValue(value any) ([]byte, error) {
+	var b bytes.Buffer
+	if err := writeConfigValue(&b, value); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeConfigValue(b *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case *configObject:
+		b.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeConfigValue(b, key); err != nil {
+				return err
+			}
+			b.WriteByte(':')
+			if err := writeConfigValue(b, v.values[key]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case []any:
+		b.WriteByte('[')
+		for i, element := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeConfigValue(b, element); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case configNumber:
+		if !jsonNumberPattern.MatchString(string(v)) {
+			return fmt.Errorf("number %s has no JSON form", v)
+		}
+		b.WriteString(string(v))
+	case string, bool, nil:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+	default:
+		return fmt.Errorf("unexpected config value %T", value)
+	}
+	return nil
+}
+
+// stripJSONComments turns JSONC into JSON: // and /* */ comments become
+// spaces (newlines are kept, so JSON error offsets still point at the right
+// line) and commas before a closing bracket or brace are dropped.
+func stripJSONComments(data []byte) ([]byte, error) {
+	out := slices.Clone(data)
+	inString := false
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated /* comment at offset %d", i)
+			}
+			for j := i; j < i+2+end+2; j++ {
+				if out[j] != '\n' {
+					out[j] = ' '
+				}
+			}
+			i += 2 + end + 1
+		}
+	}
+
+	// Drop trailing commas, now that comments cannot hide what follows them.
+	inString = false
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			next := i + 1
+			for next < len(out) && strings.IndexByte(" \t\r\n", out[next]) >= 0 {
+				next++
+			}
+			if next < len(out) && (out[next] == '}' || out[next] == ']') {
+				out[i] = ' '
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,215 @@
+package csvons
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestReadConfigFileFormats verifies that the YAML and TOML example rulers
+// read into the same rules, metadata and stem order as their JSON versions.
+func TestReadConfigFileFormats(t *testing.T) {
+	root := projectRoot()
+	tests := []struct {
+		jsonName  string
+		otherName string
+	}{
+		{"ruler_products.json", "ruler_products.yaml"},
+		{"ruler_orders.json", "ruler_orders.toml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.otherName, func(t *testing.T) {
+			expectedRules, expectedMetadata := ReadConfigFile(filepath.Join(root, "ruler", tt.jsonName))
+			rules, metadata := ReadConfigFile(filepath.Join(root, "ruler", tt.otherName))
+			if rules == nil || metadata == nil {
+				t.Fatalf("read config file error: file_name=%s", tt.otherName)
+			}
+			if !reflect.DeepEqual(metadata, expectedMetadata) {
+				t.Fatalf("metadata = %+v, expected %+v", metadata, expectedMetadata)
+			}
+			if len(rules) != len(expectedRules) {
+				t.Fatalf("got %d stems, expected %d", len(rules), len(expectedRules))
+			}
+			for stem, expected := range expectedRules {
+				if !sameJSON(t, rules[stem], expected) {
+					t.Errorf("rules of %s = %s, expected %s", stem, rules[stem], expected)
+				}
+				keys, err := ObjectKeys(rules[stem])
+				if err != nil {
+					t.Fatalf("ObjectKeys failed: %v", err)
+				}
+				expectedKeys, _ := ObjectKeys(expected)
+				if !slices.Equal(keys, expectedKeys) {
+					t.Errorf("rule order of %s = %q, expected %q", stem, keys, expectedKeys)
+				}
+			}
+		})
+	}
+}
+
+// sameJSON reports whether two JSON documents decode to equal values.
+func sameJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// TestConfigToJSON verifies the conversion of each format's syntax.
+func TestConfigToJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		input    string
+		expected string
+	}{
+		{
+			name:   "jsonc comments and trailing commas",
+			format: "jsonc",
+			input: `{
+				// Line comment.
+				"url": "http://example.com/*x*/", /* block
+				comment */ "list": [1, 2,],
+			}`,
+			expected: `{"url": "http://example.com/*x*/", "list": [1, 2]}`,
+		},
+		{
+			name:   "yaml scalars",
+			format: "yaml",
+			input: `---
+plain: hello world # comment
+hash: a#b
+quoted: "a: \"b\" # c"
+single: 'it''s'
+numbers: [1, -2.5, 1e3, 0x10]
+bools: [true, false, null, ~]
+empty:
+`,
+			expected: `{"plain": "hello world", "hash": "a#b", "quoted": "a: \"b\" # c", "single": "it's",
+				"numbers": [1, -2.5, 1e3, 16], "bools": [true, false, null, null], "empty": null}`,
+		},
+		{
+			name:   "yaml nesting",
+			format: "yaml",
+			input: `a:
+- x: 1
+  y: [1,
+      2]
+- - nested
+b: {k: v, l: [1]}
+`,
+			expected: `{"a": [{"x": 1, "y": [1, 2]}, ["nested"]], "b": {"k": "v", "l": [1]}}`,
+		},
+		{
+			name:     "yaml byte order mark and apostrophes",
+			format:   "yaml",
+			input:    "\ufeffa: [it's, b,\n  c]\nd: it's # comment\n",
+			expected: `{"a": ["it's", "b", "c"], "d": "it's"}`,
+		},
+		{
+			name:   "toml tables",
+			format: "toml",
+			input: `title = 'literal \n'
+a.b = "x\ty" # comment
+[t]
+n = 1_000
+hex = 0xff
+list = [
+  1,
+  2, # trailing
+]
+[[t.items]]
+v = true
+[[t.items]]
+v = 1.5e-3
+`,
+			expected: `{"title": "literal \\n", "a": {"b": "x\ty"},
+				"t": {"n": 1000, "hex": 255, "list": [1, 2], "items": [{"v": true}, {"v": 1.5e-3}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ConfigToJSON([]byte(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("ConfigToJSON failed: %v", err)
+			}
+			if !sameJSON(t, data, []byte(tt.expected)) {
+				t.Fatalf("ConfigToJSON = %s, expected %s", data, tt.expected)
+			}
+		})
+	}
+}
+
+// TestConfigToJSONRejects verifies that unsupported or invalid syntax is
+// reported with its line.
+func TestConfigToJSONRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		input    string
+		expected string
+	}{
+		{"yaml tab indentation", "yaml", "a:\n\tb: 1\n", "line 2: tabs"},
+		{"yaml block scalar", "yaml", "a: 1\nb: |\n  text\n", "line 2:"},
+		{"yaml anchor", "yaml", "a: &x 1\n", "line 1:"},
+		{"yaml duplicate key", "yaml", "a: 1\na: 2\n", `line 2: duplicate key "a"`},
+		{"yaml bad indentation", "yaml", "a:\n  b: 1\n    c: 2\n", "line 3:"},
+		{"yaml multiple documents", "yaml", "a: 1\n---\nb: 2\n", "line 2: multiple documents"},
+		{"toml duplicate table", "toml", "[a]\nx = 1\n[a]\n", `line 3: table "a" is defined twice`},
+		{"toml duplicate key", "toml", "x = 1\nx = 2\n", `line 2: duplicate key "x"`},
+		{"toml table under empty array", "toml", "a = []\n[a.b]\n", `line 2: key "a" is not a table`},
+		{"toml dotted key under empty array", "toml", "a = []\na.b = 1\n", `line 2: key "a" is not a table`},
+		{"toml dotted key under value array", "toml", "a = [{ b = 1 }]\na.c = 1\n", `line 2: key "a" is not a table`},
+		{"toml array of tables after value array", "toml", "a = [1]\n[[a]]\n", `line 2: key "a" is not an array of tables`},
+		{"toml date", "toml", "d = 2024-01-02\n", "line 1: dates"},
+		{"toml trailing text", "toml", "x = 1 2\n", "line 1: unexpected text"},
+		{"toml multi-line string", "toml", "x = \"\"\"a\"\"\"\n", "line 1: multi-line"},
+		{"jsonc unterminated comment", "jsonc", "{} /* x", "unterminated"},
+		{"unknown format", "xml", "<a/>", "unknown config format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ConfigToJSON([]byte(tt.input), tt.format)
+			if err == nil {
+				t.Fatalf("ConfigToJSON accepted %q", tt.input)
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("error = %q, expected it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+// TestReadConfigFileFormatOverride verifies that an explicit format wins
+// over the file extension, so a ruler.json may hold JSONC.
+func TestReadConfigFileFormatOverride(t *testing.T) {
+	configFileName := filepath.Join(t.TempDir(), "ruler.json")
+	config := `{
+		// Weapons need unique IDs.
+		"weapons": {"unique": {"fields": ["ID"]}},
+		"csvons_metadata": {"csv_file_folder": ".", "name_index": 0, "data_index": 1, "extension": ".csv"},
+	}`
+	if err := os.WriteFile(configFileName, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+
+	if rules, metadata := ReadConfigFile(configFileName); rules != nil || metadata != nil {
+		t.Fatalf("ReadConfigFile accepted comments in a .json file")
+	}
+	rules, metadata := ReadConfigFileFormat(configFileName, "jsonc")
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+	if expected := []string{"weapons"}; !slices.Equal(metadata.Stems, expected) {
+		t.Fatalf("metadata.Stems = %q, expected %q", metadata.Stems, expected)
+	}
+}
@@ -0,0 +1,350 @@
+package csvons
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseTOMLConfig decodes the TOML subset rulers need into a configuration
+// value (see marshalConfigValue):
+//
+//   - key = value pairs with bare, quoted and dotted keys
+//   - [table] headers and [[array.of.tables]]
+//   - "basic" and 'literal' strings, integers (also 0x, 0o and 0b, with _),
+//     floats, booleans, arrays over several lines and inline tables
+//   - # comments
+//
+// Multi-line strings, dates and times, inf and nan are rejected.
+func parseTOMLConfig(data []byte) (value any, err error) {
+	defer recoverConfigSyntax(&err)
+
+	p := &tomlParser{
+		s:           string(data),
+		defined:     make(map[*configObject]bool),
+		tableArrays: make(map[tomlArrayKey]bool),
+	}
+	root := newConfigObject()
+	current := root
+	for {
+		p.skipBlank()
+		if p.i >= len(p.s) {
+			return root, nil
+		}
+		if p.s[p.i] == '[' {
+			current = p.parseHeader(root)
+		} else {
+			p.parseKeyValue(current)
+		}
+		p.endLine()
+	}
+}
+
+type tomlParser struct {
+	s           string
+	i           int
+	defined     map[*configObject]bool // Tables opened by a [table] header.
+	tableArrays map[tomlArrayKey]bool  // Arrays created by [[array]] headers.
+}
+
+// tomlArrayKey names the key of an array of tables within its table.
+type tomlArrayKey struct {
+	parent *configObject
+	key    string
+}
+
+// fail aborts with a syntax error at the current line.
+func (p *tomlParser) fail(format string, args ...any) {
+	failConfigSyntax(strings.Count(p.s[:min(p.i, len(p.s))], "\n")+1, format, args...)
+}
+
+// skipSpaces skips spaces and tabs.
+func (p *tomlParser) skipSpaces() {
+	for p.i < len(p.s) && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+	for p.i < len(p.s) {
+		switch p.s[p.i] {
+		case ' ', '\t', '\r', '\n':
+			p.i++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	for p.i < len(p.s) && p.s[p.i] != '\n' {
+		p.i++
+	}
+}
+
+// endLine requires the rest of the line to be blank or a comment.
+func (p *tomlParser) endLine() {
+	p.skipSpaces()
+	if p.i < len(p.s) && p.s[p.i] == '#' {
+		p.skipComment()
+	}
+	if p.i < len(p.s) && p.s[p.i] == '\r' {
+		p.i++
+	}
+	if p.i < len(p.s) && p.s[p.i] != '\n' {
+		p.fail("unexpected text %q", strings.SplitN(p.s[p.i:], "\n", 2)[0])
+	}
+}
+
+// parseHeader parses a [table] or [[array.of.tables]] header and returns
+// the table that following keys belong to.
+func (p *tomlParser) parseHeader(root *configObject) *configObject {
+	array := strings.HasPrefix(p.s[p.i:], "[[")
+	if array {
+		p.i += 2
+	} else {
+		p.i++
+	}
+	path := p.parseKeyPath()
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	p.skipSpaces()
+	if !strings.HasPrefix(p.s[p.i:], closing) {
+		p.fail("expected %s after table name", closing)
+	}
+	p.i += len(closing)
+
+	parent := p.descend(root, path[:len(path)-1])
+	key := path[len(path)-1]
+	existing, ok := parent.get(key)
+	if array {
+		arrayKey := tomlArrayKey{parent: parent, key: key}
+		if ok && !p.tableArrays[arrayKey] {
+			p.fail("key %q is not an array of tables", strings.Join(path, "."))
+		}
+		tables, _ := existing.([]any)
+		table := newConfigObject()
+		parent.set(key, append(tables, table))
+		p.tableArrays[arrayKey] = true
+		return table
+	}
+
+	if !ok {
+		table := newConfigObject()
+		parent.set(key, table)
+		p.defined[table] = true
+		return table
+	}
+	table, isTable := existing.(*configObject)
+	if !isTable || p.defined[table] {
+		p.fail("table %q is defined twice", strings.Join(path, "."))
+	}
+	p.defined[table] = true
+	return table
+}
+
+// descend returns the table at path below object, creating missing tables.
+// A path through an array of tables continues in its last table; other
+// arrays, such as a = [] or a = [{ b = 1 }], cannot be extended.
+func (p *tomlParser) descend(object *configObject, path []string) *configObject {
+	for _, key := range path {
+		value, ok := object.get(key)
+		if !ok {
+			table := newConfigObject()
+			object.set(key, table)
+			object = table
+			continue
+		}
+		switch v := value.(type) {
+		case *configObject:
+			object = v
+		case []any:
+			if !p.tableArrays[tomlArrayKey{parent: object, key: key}] || len(v) == 0 {
+				p.fail("key %q is not a table", key)
+			}
+			object = v[len(v)-1].(*configObject)
+		default:
+			p.fail("key %q is not a table", key)
+		}
+	}
+	return object
+}
+
+// parseKeyValue parses "key = value" into object.
+func (p *tomlParser) parseKeyValue(object *configObject) {
+	path := p.parseKeyPath()
+	p.skipSpaces()
+	if p.i >= len(p.s) || p.s[p.i] != '=' {
+		p.fail("expected = after key %q", strings.Join(path, "."))
+	}
+	p.i++
+	value := p.parseValue()
+
+	parent := p.descend(object, path[:len(path)-1])
+	key := path[len(path)-1]
+	if _, duplicate := parent.get(key); duplicate {
+		p.fail("duplicate key %q", strings.Join(path, "."))
+	}
+	parent.set(key, value)
+}
+
+// tomlBareKeyPattern matches the characters of a bare key.
+var tomlBareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// parseKeyPath parses a possibly dotted key.
+func (p *tomlParser) parseKeyPath() []string {
+	var path []string
+	for {
+		p.skipSpaces()
+		switch {
+		case p.i < len(p.s) && (p.s[p.i] == '"' || p.s[p.i] == '\''):
+			path = append(path, p.parseString())
+		default:
+			bare := tomlBareKeyPattern.FindString(p.s[p.i:])
+			if bare == "" {
+				p.fail("expected a key")
+			}
+			path = append(path, bare)
+			p.i += len(bare)
+		}
+		p.skipSpaces()
+		if p.i >= len(p.s) || p.s[p.i] != '.' {
+			return path
+		}
+		p.i++
+	}
+}
+
+func (p *tomlParser) parseValue() any {
+	p.skipSpaces()
+	if p.i >= len(p.s) {
+		p.fail("missing value")
+	}
+	switch c := p.s[p.i]; {
+	case c == '"' || c == '\'':
+		return p.parseString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case strings.HasPrefix(p.s[p.i:], "true"):
+		p.i += len("true")
+		return true
+	case strings.HasPrefix(p.s[p.i:], "false"):
+		p.i += len("false")
+		return false
+	}
+	return p.parseNumber()
+}
+
+// parseString parses a basic string, which uses JSON escapes, or a literal
+// string.
+func (p *tomlParser) parseString() string {
+	quote := p.s[p.i]
+	if strings.HasPrefix(p.s[p.i:], strings.Repeat(string(quote), 3)) {
+		p.fail("multi-line strings are not supported")
+	}
+	for j := p.i + 1; j < len(p.s); j++ {
+		switch c := p.s[j]; {
+		case c == '\n':
+			p.fail("unterminated string")
+		case c == '\\' && quote == '"':
+			j++
+		case c == quote:
+			text := p.s[p.i+1 : j]
+			if quote == '"' {
+				if err := json.Unmarshal([]byte(p.s[p.i:j+1]), &text); err != nil {
+					p.fail("invalid string %s", p.s[p.i:j+1])
+				}
+			}
+			p.i = j + 1
+			return text
+		}
+	}
+	p.fail("unterminated string")
+	return ""
+}
+
+func (p *tomlParser) parseArray() []any {
+	p.i++
+	array := []any{}
+	for {
+		p.skipBlank()
+		if p.i < len(p.s) && p.s[p.i] == ']' {
+			p.i++
+			return array
+		}
+		array = append(array, p.parseValue())
+		p.skipBlank()
+		if p.i < len(p.s) && p.s[p.i] == ',' {
+			p.i++
+			continue
+		}
+		if p.i < len(p.s) && p.s[p.i] == ']' {
+			p.i++
+			return array
+		}
+		p.fail("expected , or ] in array")
+	}
+}
+
+func (p *tomlParser) parseInlineTable() *configObject {
+	p.i++
+	table := newConfigObject()
+	for {
+		p.skipSpaces()
+		if p.i < len(p.s) && p.s[p.i] == '}' {
+			p.i++
+			return table
+		}
+		p.parseKeyValue(table)
+		p.skipSpaces()
+		if p.i < len(p.s) && p.s[p.i] == ',' {
+			p.i++
+			continue
+		}
+		if p.i < len(p.s) && p.s[p.i] == '}' {
+			p.i++
+			return table
+		}
+		p.fail("expected , or } in inline table")
+	}
+}
+
+var (
+	tomlNumberPattern = regexp.MustCompile(`^[-+0-9A-Za-z_.:]+`)
+	tomlDatePattern   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}|^\d{2}:\d{2}`)
+	tomlIntPattern    = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)$`)
+	tomlFloatPattern  = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// parseNumber parses an integer or float into its JSON form.
+func (p *tomlParser) parseNumber() configNumber {
+	token := tomlNumberPattern.FindString(p.s[p.i:])
+	switch {
+	case token == "":
+		p.fail("invalid value")
+	case tomlDatePattern.MatchString(token):
+		p.fail("dates and times are not supported; quote the value")
+	}
+	p.i += len(token)
+
+	digits := strings.ReplaceAll(token, "_", "")
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0o") || strings.HasPrefix(digits, "0b") {
+		n, err := strconv.ParseInt(digits, 0, 64)
+		if err != nil {
+			p.fail("invalid integer %s", token)
+		}
+		return configNumber(strconv.FormatInt(n, 10))
+	}
+	if !tomlIntPattern.MatchString(digits) && !tomlFloatPattern.MatchString(digits) {
+		p.fail("invalid value %s", token)
+	}
+	return configNumber(strings.TrimPrefix(digits, "+"))
+}
@@ -0,0 +1,446 @@
+package csvons
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseYAMLConfig decodes the YAML subset rulers need into a configuration
+// value (see marshalConfigValue):
+//
+//   - block mappings and sequences, nested by indentation (spaces only),
+//     including "- key: value" items and sequences at their key's indent
+//   - flow sequences and mappings ([a, b], {key: value}), also over several lines
+//   - plain, 'single' and "double" quoted scalars; plain scalars resolve to
+//     null (null, ~), booleans (true, false), numbers or strings
+//   - # comments and a leading --- document marker
+//
+// Anchors, aliases, tags, block scalars (| and >), multi-line plain scalars
+// and multiple documents are rejected. Quote values that start with YAML
+// indicators, such as "|" or ">=".
+func parseYAMLConfig(data []byte) (value any, err error) {
+	defer recoverConfigSyntax(&err)
+
+	p := &yamlParser{lines: splitYAMLLines(string(data))}
+	if len(p.lines) == 0 {
+		failConfigSyntax(1, "empty document")
+	}
+	value = p.parseBlock(p.lines[0].indent)
+	if p.pos < len(p.lines) {
+		failConfigSyntax(p.lines[p.pos].number, "unexpected indentation")
+	}
+	return value, nil
+}
+
+// yamlLine is a non-blank line of a YAML document without its comment.
+type yamlLine struct {
+	number  int    // 1-based line number.
+	indent  int    // Number of leading spaces.
+	content string // Text after the indentation.
+}
+
+// splitYAMLLines returns the non-blank lines of a document, without a
+// leading UTF-8 byte order mark.
+func splitYAMLLines(text string) []yamlLine {
+	text = strings.TrimPrefix(text, "\ufeff")
+	var lines []yamlLine
+	for i, raw := range strings.Split(text, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		content := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(content)
+		if strings.HasPrefix(content, "\t") {
+			failConfigSyntax(i+1, "tabs are not allowed in indentation")
+		}
+		content = strings.TrimRight(stripYAMLComment(content), " \t")
+		if content == "" {
+			continue
+		}
+		if indent == 0 && (content == "---" || strings.HasPrefix(content, "--- ")) {
+			if len(lines) > 0 {
+				failConfigSyntax(i+1, "multiple documents are not supported")
+			}
+			if content = strings.TrimSpace(content[3:]); content == "" {
+				continue
+			}
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: indent, content: content})
+	}
+	return lines
+}
+
+// stripYAMLComment removes a # comment, which starts a line or follows
+// whitespace, outside quoted scalars.
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && yamlScalarStart(s, i):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseBlock parses the node starting at the current line, which has the
+// given indent.
+func (p *yamlParser) parseBlock(indent int) any {
+	line := p.lines[p.pos]
+	if isYAMLSequenceItem(line.content) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitYAMLMapping(line.content, line.number); ok {
+		return p.parseMapping(indent)
+	}
+	p.pos++
+	return parseYAMLInline(p.continueFlow(line.content), line.number)
+}
+
+// parseNested parses the value of a key or sequence item whose own line
+// ends after the indicator: a deeper block, a sequence at the same indent
+// when sameIndentSequence is set (YAML allows it for mapping values), or
+// null.
+func (p *yamlParser) parseNested(indent int, sameIndentSequence bool) any {
+	if p.pos >= len(p.lines) {
+		return nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent {
+		return p.parseBlock(next.indent)
+	}
+	if sameIndentSequence && next.indent == indent && isYAMLSequenceItem(next.content) {
+		return p.parseSequence(indent)
+	}
+	return nil
+}
+
+func (p *yamlParser) parseMapping(indent int) *configObject {
+	object := newConfigObject()
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if isYAMLSequenceItem(line.content) {
+			failConfigSyntax(line.number, "unexpected sequence item in a mapping")
+		}
+		key, rest, ok := splitYAMLMapping(line.content, line.number)
+		if !ok {
+			failConfigSyntax(line.number, "expected a key: value pair")
+		}
+		if _, duplicate := object.get(key); duplicate {
+			failConfigSyntax(line.number, "duplicate key %q", key)
+		}
+		p.pos++
+
+		if rest == "" {
+			object.set(key, p.parseNested(indent, true))
+		} else {
+			object.set(key, parseYAMLInline(p.continueFlow(rest), line.number))
+		}
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		failConfigSyntax(p.lines[p.pos].number, "unexpected indentation")
+	}
+	return object
+}
+
+func (p *yamlParser) parseSequence(indent int) []any {
+	sequence := []any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].content) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.content[1:], " ")
+		if rest == "" {
+			p.pos++
+			sequence = append(sequence, p.parseNested(indent, false))
+			continue
+		}
+
+		// Parse the item as a block at its own column, so "- key: value"
+		// continues with keys at that column and "- - a" nests.
+		column := indent + len(line.content) - len(rest)
+		p.lines[p.pos] = yamlLine{number: line.number, indent: column, content: rest}
+		sequence = append(sequence, p.parseBlock(column))
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		failConfigSyntax(p.lines[p.pos].number, "unexpected indentation")
+	}
+	return sequence
+}
+
+// continueFlow appends the following lines to a flow collection that does
+// not close on its own line.
+func (p *yamlParser) continueFlow(text string) string {
+	if !strings.HasPrefix(text, "[") && !strings.HasPrefix(text, "{") {
+		return text
+	}
+	for yamlFlowDepth(text) > 0 {
+		if p.pos >= len(p.lines) {
+			failConfigSyntax(p.lines[len(p.lines)-1].number, "unterminated flow collection")
+		}
+		text += " " + p.lines[p.pos].content
+		p.pos++
+	}
+	return text
+}
+
+// yamlFlowDepth returns the number of brackets and braces left open in s.
+func yamlFlowDepth(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && yamlScalarStart(s, i):
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// yamlScalarStart reports whether s[i] may begin a scalar, so that a quote
+// there opens a quoted scalar rather than being part of a plain one (it's).
+func yamlScalarStart(s string, i int) bool {
+	return i == 0 || strings.IndexByte(" \t[{,:", s[i-1]) >= 0
+}
+
+func isYAMLSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// splitYAMLMapping splits a "key: value" line. The key may be quoted; a
+// plain key ends at the first ": " or a trailing ":".
+func splitYAMLMapping(content string, lineNumber int) (key, rest string, ok bool) {
+	if content == "" || strings.IndexByte("[{", content[0]) >= 0 {
+		return "", "", false
+	}
+	if content[0] == '"' || content[0] == '\'' {
+		key, end := parseYAMLQuoted(content, 0, lineNumber)
+		after := strings.TrimLeft(content[end:], " ")
+		if !strings.HasPrefix(after, ":") || len(after) > 1 && after[1] != ' ' {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(after[1:]), true
+	}
+
+	if i := strings.Index(content, ": "); i >= 0 {
+		return strings.TrimRight(content[:i], " "), strings.TrimSpace(content[i+2:]), true
+	}
+	if strings.HasSuffix(content, ":") {
+		return strings.TrimRight(content[:len(content)-1], " "), "", true
+	}
+	return "", "", false
+}
+
+// parseYAMLInline parses a scalar or flow collection that makes up the rest
+// of a line.
+func parseYAMLInline(text string, lineNumber int) any {
+	switch text[0] {
+	case '|', '>':
+		failConfigSyntax(lineNumber, "block scalars (| and >) are not supported; quote the value")
+	case '&', '*', '!':
+		failConfigSyntax(lineNumber, "anchors, aliases and tags are not supported")
+	case '[', '{', '"', '\'':
+		flow := &yamlFlow{s: text, line: lineNumber}
+		value := flow.parseValue()
+		if flow.skipSpaces(); flow.i < len(text) {
+			failConfigSyntax(lineNumber, "unexpected text %q", text[flow.i:])
+		}
+		return value
+	}
+	return resolveYAMLScalar(text)
+}
+
+// yamlFlow parses flow collections and quoted scalars.
+type yamlFlow struct {
+	s    string
+	i    int
+	line int
+}
+
+func (f *yamlFlow) skipSpaces() {
+	for f.i < len(f.s) && (f.s[f.i] == ' ' || f.s[f.i] == '\t') {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) parseValue() any {
+	f.skipSpaces()
+	if f.i >= len(f.s) {
+		failConfigSyntax(f.line, "missing value")
+	}
+	switch f.s[f.i] {
+	case '[':
+		f.i++
+		sequence := []any{}
+		for {
+			if f.skipSpaces(); f.i < len(f.s) && f.s[f.i] == ']' {
+				f.i++
+				return sequence
+			}
+			sequence = append(sequence, f.parseValue())
+			f.closeOrContinue(']')
+			if f.s[f.i-1] == ']' {
+				return sequence
+			}
+		}
+	case '{':
+		f.i++
+		object := newConfigObject()
+		for {
+			if f.skipSpaces(); f.i < len(f.s) && f.s[f.i] == '}' {
+				f.i++
+				return object
+			}
+			key := f.parseKey()
+			if _, duplicate := object.get(key); duplicate {
+				failConfigSyntax(f.line, "duplicate key %q", key)
+			}
+			object.set(key, f.parseValue())
+			f.closeOrContinue('}')
+			if f.s[f.i-1] == '}' {
+				return object
+			}
+		}
+	case '"', '\'':
+		value, end := parseYAMLQuoted(f.s, f.i, f.line)
+		f.i = end
+		return value
+	}
+
+	start := f.i
+	for f.i < len(f.s) && strings.IndexByte(",]}", f.s[f.i]) < 0 {
+		f.i++
+	}
+	return resolveYAMLScalar(strings.TrimSpace(f.s[start:f.i]))
+}
+
+// closeOrContinue consumes the comma after a flow element or the closing
+// bracket.
+func (f *yamlFlow) closeOrContinue(closing byte) {
+	f.skipSpaces()
+	if f.i < len(f.s) && (f.s[f.i] == ',' || f.s[f.i] == closing) {
+		f.i++
+		return
+	}
+	failConfigSyntax(f.line, "expected , or %c in flow collection", closing)
+}
+
+// parseKey parses a flow mapping key and its colon.
+func (f *yamlFlow) parseKey() string {
+	f.skipSpaces()
+	var key string
+	if f.i < len(f.s) && (f.s[f.i] == '"' || f.s[f.i] == '\'') {
+		key, f.i = parseYAMLQuoted(f.s, f.i, f.line)
+		f.skipSpaces()
+	} else {
+		start := f.i
+		for f.i < len(f.s) && strings.IndexByte(":,]}", f.s[f.i]) < 0 {
+			f.i++
+		}
+		key = strings.TrimSpace(f.s[start:f.i])
+	}
+	if f.i >= len(f.s) || f.s[f.i] != ':' {
+		failConfigSyntax(f.line, "expected : after key %q", key)
+	}
+	f.i++
+	return key
+}
+
+// parseYAMLQuoted parses the quoted scalar starting at s[i] and returns it
+// with the index after its closing quote. Double-quoted scalars use JSON
+// escapes; in single-quoted ones a doubled quote stands for one.
+func parseYAMLQuoted(s string, i, lineNumber int) (string, int) {
+	if s[i] == '\'' {
+		var b strings.Builder
+		for j := i + 1; j < len(s); j++ {
+			if s[j] != '\'' {
+				b.WriteByte(s[j])
+				continue
+			}
+			if j+1 < len(s) && s[j+1] == '\'' {
+				b.WriteByte('\'')
+				j++
+				continue
+			}
+			return b.String(), j + 1
+		}
+		failConfigSyntax(lineNumber, "unterminated single-quoted string")
+	}
+
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			var value string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &value); err != nil {
+				failConfigSyntax(lineNumber, "invalid double-quoted string %s", s[i:j+1])
+			}
+			return value, j + 1
+		}
+	}
+	failConfigSyntax(lineNumber, "unterminated double-quoted string")
+	return "", 0
+}
+
+var (
+	yamlIntPattern      = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlRadixIntPattern = regexp.MustCompile(`^0x[0-9a-fA-F]+$|^0o[0-7]+$`)
+	yamlFloatPattern    = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// resolveYAMLScalar types a plain scalar by the YAML core schema.
+func resolveYAMLScalar(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if yamlIntPattern.MatchString(s) {
+		sign := ""
+		if s[0] == '-' || s[0] == '+' {
+			sign, s = strings.TrimPrefix(s[:1], "+"), s[1:]
+		}
+		if digits := strings.TrimLeft(s, "0"); digits != "" {
+			return configNumber(sign + digits)
+		}
+		return configNumber("0")
+	}
+	if yamlRadixIntPattern.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return configNumber(strconv.FormatInt(n, 10))
+		}
+	}
+	if yamlFloatPattern.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return configNumber(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return s
+}
@@ -0,0 +1,103 @@
+package csvons
+
+import (
+	"log"
+	"strings"
+)
+
+// ArrayShapeTest validates the shape of array-like cells row by row.
+//
+// For each rule it splits the cell of Field by Lev1Separator and checks:
+//  1. The number of elements against the optional Elements bounds
+//  2. That every element splits into exactly Arity parts by Lev2Separator
+//  3. That each column in SameLengthAs has the same number of elements in that row
+//
+// Unlike NestedField, which only logs out-of-range indices, every mismatch
+// here is reported as a validation error.
+func ArrayShapeTest(stem string, ruler []ArrayShape, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "array_shape"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "array_shape"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, shape := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "array_shape", Field: shape.Field}
+
+		// Collect the parallel columns' cells by row before walking the main column.
+		parallelCells := make(map[string]map[int]string, len(shape.SameLengthAs))
+		for _, other := range shape.SameLengthAs {
+			otherExpr := &PlainField{}
+			otherExpr.Init(metadata, other)
+			cells := make(map[int]string)
+			for occurrence := range src.occurrences(otherExpr, other, ctx) {
+				cells[occurrence.Row] = occurrence.Value
+			}
+			parallelCells[other] = cells
+		}
+
+		fieldExpr := &PlainField{}
+		fieldExpr.Init(metadata, shape.Field)
+		for occurrence := range src.occurrences(fieldExpr, shape.Field, ctx) {
+			ctx.Row = rowPointer(occurrence.Row)
+			ctx.Value = occurrence.Value
+			elements := splitArrayCell(occurrence.Value, metadata.Lev1Separator)
+
+			if shape.Elements != nil {
+				if shape.Elements.Min != nil && len(elements) < *shape.Elements.Min {
+					failValidation(ctx, "src_field [%s] value [%s] has [%d] elements, less than min [%d]", shape.Field, occurrence.Value, len(elements), *shape.Elements.Min)
+					return
+				}
+				if shape.Elements.Max != nil && len(elements) > *shape.Elements.Max {
+					failValidation(ctx, "src_field [%s] value [%s] has [%d] elements, more than max [%d]", shape.Field, occurrence.Value, len(elements), *shape.Elements.Max)
+					return
+				}
+			}
+
+			if shape.Arity > 0 {
+				for i, element := range elements {
+					if parts := strings.Split(element, metadata.Lev2Separator); len(parts) != shape.Arity {
+						failValidation(ctx, "src_field [%s] element [%d] value [%s] has [%d] parts, expected [%d]", shape.Field, i, element, len(parts), shape.Arity)
+						return
+					}
+				}
+			}
+
+			for _, other := range shape.SameLengthAs {
+				otherCell, ok := parallelCells[other][occurrence.Row]
+				if !ok {
+					continue
+				}
+				if otherLen := len(splitArrayCell(otherCell, metadata.Lev1Separator)); otherLen != len(elements) {
+					failValidation(
+						ctx,
+						"src_field [%s] has [%d] elements but [%s] has [%d] at row [%d]",
+						shape.Field,
+						len(elements),
+						other,
+						otherLen,
+						occurrence.Row,
+					)
+					return
+				}
+			}
+		}
+
+		log.Printf("src_field [%s] array shapes are valid", shape.Field)
+	}
+}
+
+// splitArrayCell splits a cell into its lev1 elements.
+// An empty cell has no elements.
+func splitArrayCell(cell, separator string) []string {
+	if cell == "" {
+		return nil
+	}
+	return strings.Split(cell, separator)
+}
@@ -0,0 +1,69 @@
+package csvons
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestArrayShapeOrders validates array_shape constraints using the orders test data.
+// Items and Scores are parallel arrays of two-part elements.
+func TestArrayShapeOrders(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_orders.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "array_shape" {
+				var shapes []ArrayShape
+				if err := json.Unmarshal(v, &shapes); err != nil {
+					t.Fatalf("error unmarshalling array_shape: %v", err)
+				}
+				ArrayShapeTest(stem, shapes, metadata)
+			}
+		}
+	}
+}
+
+// TestArrayShapeRejects verifies element count, arity and parallel length checks.
+func TestArrayShapeRejects(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "orders", "Items,Scores\n\"a:1;b:2\",\"1:2;3:4\"\n\"c:3;d\",\"5:6\"\n")
+	metadata := testMetadata(dir)
+
+	three := 3
+	tests := []struct {
+		name    string
+		shape   ArrayShape
+		row     int
+		message string
+	}{
+		{"too few elements", ArrayShape{Field: "Items", Elements: &CountRange{Min: &three}}, 2, "less than min [3]"},
+		{"wrong arity", ArrayShape{Field: "Items", Arity: 2}, 3, "element [1] value [d] has [1] parts"},
+		{"parallel length", ArrayShape{Field: "Items", SameLengthAs: []string{"Scores"}}, 3, "but [Scores] has [1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := expectValidationError(t, func() { ArrayShapeTest("orders", []ArrayShape{tt.shape}, metadata) })
+			if err.Row == nil || *err.Row != tt.row {
+				t.Fatalf("unexpected row: %#v", err.Row)
+			}
+			if !strings.Contains(err.Message, tt.message) {
+				t.Fatalf("unexpected message: %q", err.Message)
+			}
+		})
+	}
+}
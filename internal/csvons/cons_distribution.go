@@ -0,0 +1,122 @@
+package csvons
+
+import (
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DistributionTest validates weighted-probability cells.
+//
+// For each rule it splits the shared column of Key and Weight into lev1
+// elements, takes the key and weight from the same element, and checks per
+// distribution (a row, or a group of rows) that:
+//  1. Every weight is a positive, finite number
+//  2. No key appears twice
+//  3. The weights add up to Sum within Tolerance, if Sum is set
+func DistributionTest(stem string, ruler []Distribution, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "distribution"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "distribution"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, distribution := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "distribution", Field: distribution.Key}
+
+		// Key and weight must address parts of the same lev1 element.
+		keyExpr, keyOk := GenerateFieldExpr(metadata, distribution.Key).(*NestedField)
+		weightExpr, weightOk := GenerateFieldExpr(metadata, distribution.Weight).(*NestedField)
+		if !keyOk || !weightOk || keyExpr.fieldName != weightExpr.fieldName {
+			failRuntime(ctx, "key [%s] and weight [%s] must be nested fields of the same column", distribution.Key, distribution.Weight)
+			return
+		}
+
+		// Resolve the distribution each row belongs to.
+		rowScopes := map[int]string{}
+		switch distribution.Scope {
+		case "", "row":
+		case "group":
+			if distribution.GroupBy == "" {
+				failRuntime(ctx, "group_by is required for scope [group]")
+				return
+			}
+			rowScopes = src.rowValues(distribution.GroupBy, ctx)
+		default:
+			failRuntime(ctx, "scope [%s] is not row or group", distribution.Scope)
+			return
+		}
+
+		cells := src.rowValues(keyExpr.fieldName, ctx)
+		rows := make([]int, 0, len(cells))
+		for row := range cells {
+			rows = append(rows, row)
+		}
+		slices.Sort(rows)
+
+		seenKeys := make(map[string]map[string]int)
+		sums := make(map[string]float64)
+		firstRows := make(map[string]int)
+		var scopes []string
+		for _, row := range rows {
+			ctx.Row = rowPointer(row)
+			scope := "row " + strconv.Itoa(row)
+			if distribution.Scope == "group" {
+				scope = rowScopes[row]
+				if scope == "" {
+					ctx.Value = ""
+					failValidation(ctx, "src_field [%s] row has no group_by [%s] value", keyExpr.fieldName, distribution.GroupBy)
+					return
+				}
+			}
+			if _, ok := seenKeys[scope]; !ok {
+				seenKeys[scope] = make(map[string]int)
+				firstRows[scope] = row
+				scopes = append(scopes, scope)
+			}
+
+			for _, element := range splitArrayCell(cells[row], metadata.Lev1Separator) {
+				ctx.Value = element
+				parts := strings.Split(element, metadata.Lev2Separator)
+				if keyExpr.index >= len(parts) || weightExpr.index >= len(parts) {
+					failValidation(ctx, "src_field [%s] element [%s] has no key or weight", keyExpr.fieldName, element)
+					return
+				}
+				key, weightVal := parts[keyExpr.index], parts[weightExpr.index]
+
+				weight, err := strconv.ParseFloat(weightVal, 64)
+				if err != nil || math.IsNaN(weight) || math.IsInf(weight, 0) || weight <= 0 {
+					failValidation(ctx, "src_field [%s] key [%s] weight [%s] is not a positive number", keyExpr.fieldName, key, weightVal)
+					return
+				}
+				if prevRow, ok := seenKeys[scope][key]; ok {
+					failValidation(ctx, "src_field [%s] key [%s] already appears in the distribution at row [%d]", keyExpr.fieldName, key, prevRow)
+					return
+				}
+				seenKeys[scope][key] = row
+				sums[scope] += weight
+			}
+		}
+
+		if distribution.Sum != nil {
+			for _, scope := range scopes {
+				if math.Abs(sums[scope]-*distribution.Sum) > distribution.Tolerance {
+					ctx.Row = rowPointer(firstRows[scope])
+					ctx.Value = formatFloat(sums[scope])
+					failValidation(ctx, "src_field [%s] weights of distribution [%s] sum to [%s], expected [%v]", keyExpr.fieldName, scope, formatFloat(sums[scope]), *distribution.Sum)
+					return
+				}
+			}
+		}
+
+		log.Printf("src_field [%s] distributions are valid", keyExpr.fieldName)
+	}
+}
@@ -0,0 +1,132 @@
+package csvons
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDistributionOrders validates distribution constraints using the orders test data.
+// Items holds item:weight pairs.
+func TestDistributionOrders(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_orders.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "distribution" {
+				var distribution []Distribution
+				if err := json.Unmarshal(v, &distribution); err != nil {
+					t.Fatalf("error unmarshalling distribution: %v", err)
+				}
+				DistributionTest(stem, distribution, metadata)
+			}
+		}
+	}
+}
+
+// TestDistributionRejects verifies weight, duplicate key and sum checks.
+func TestDistributionRejects(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+	hundred := 100.0
+
+	tests := []struct {
+		name    string
+		content string
+		rule    Distribution
+		row     int
+		message string
+	}{
+		{
+			"non-positive weight",
+			"Drops\n\"a:60;b:40\"\n\"a:100;b:0\"\n",
+			Distribution{Key: "Drops{0}", Weight: "Drops{1}"},
+			3,
+			"weight [0] is not a positive number",
+		},
+		{
+			"NaN weight",
+			"Drops\n\"a:NaN;b:100\"\n",
+			Distribution{Key: "Drops{0}", Weight: "Drops{1}", Sum: &hundred},
+			2,
+			"weight [NaN] is not a positive number",
+		},
+		{
+			"Inf weight",
+			"Drops\n\"a:+Inf\"\n",
+			Distribution{Key: "Drops{0}", Weight: "Drops{1}"},
+			2,
+			"weight [+Inf] is not a positive number",
+		},
+		{
+			"duplicate key in row",
+			"Drops\n\"a:60;a:40\"\n",
+			Distribution{Key: "Drops{0}", Weight: "Drops{1}"},
+			2,
+			"key [a] already appears",
+		},
+		{
+			"row sum",
+			"Drops\n\"a:60;b:40\"\n\"a:60;b:30\"\n",
+			Distribution{Key: "Drops{0}", Weight: "Drops{1}", Sum: &hundred},
+			3,
+			"sum to [90], expected [100]",
+		},
+		{
+			"duplicate key in group",
+			"Group,Drops\ng,\"a:60\"\ng,\"a:40\"\n",
+			Distribution{Key: "Drops{0}", Weight: "Drops{1}", Scope: "group", GroupBy: "Group"},
+			3,
+			"key [a] already appears in the distribution at row [2]",
+		},
+		{
+			"missing group",
+			"Group,Drops\ng,\"a:60\"\n,\"a:40\"\n",
+			Distribution{Key: "Drops{0}", Weight: "Drops{1}", Scope: "group", GroupBy: "Group"},
+			3,
+			"row has no group_by [Group] value",
+		},
+		{
+			"multi-valued group",
+			"Group,Drops\n\"g;h\",\"a:100\"\n",
+			Distribution{Key: "Drops{0}", Weight: "Drops{1}", Scope: "group", GroupBy: "Group[]"},
+			2,
+			"field expression [Group[]] yields more than one value in a row",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestCsv(t, dir, "drops", tt.content)
+			err := expectValidationError(t, func() { DistributionTest("drops", []Distribution{tt.rule}, metadata) })
+			if err.Row == nil || *err.Row != tt.row {
+				t.Fatalf("unexpected row: %#v", err.Row)
+			}
+			if !strings.Contains(err.Message, tt.message) {
+				t.Fatalf("unexpected message: %q", err.Message)
+			}
+		})
+	}
+}
+
+// TestDistributionGroupSum verifies that grouped weights are summed across rows.
+func TestDistributionGroupSum(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "drops", "Group,Drops\ng,\"a:60\"\ng,\"b:40\"\nh,\"a:100\"\n")
+
+	hundred := 100.0
+	DistributionTest("drops", []Distribution{{Key: "Drops{0}", Weight: "Drops{1}", Sum: &hundred, Scope: "group", GroupBy: "Group"}}, testMetadata(dir))
+}
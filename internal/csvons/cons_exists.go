@@ -9,12 +9,21 @@ import (
 //
 // For each rule in the ruler slice, this function:
 //  1. Reads the source CSV file using the stem parameter
-//  2. Reads the destination CSV file specified by each rule's DstFileStem
-//  3. For each field pair, extracts values using field expressions
-//  4. Verifies every source value exists in the destination values
+//  2. Indexes each destination field expression of the rule's DstFileStem
+//  3. For each field pair, extracts source values using field expressions
+//  4. Verifies every source value exists in the destination index
 //
-// The function uses a cache (cacheDstFieldVals) to avoid redundant lookups
-// and a searchedFields map to skip already-verified source values.
+// Destination indexes are hash maps shared through metadata.Indexes, so each
+// destination column is read and indexed once per run no matter how many
+// rules or stems reference it; the destination file is not read at all when
+// every column it is needed for is already indexed. A searchedFields map
+// skips already-verified source values. In streaming mode the source and
+// destination files are read record by record; only the destination index
+// and the searched values are kept.
+//
+// For destinations too large to index in memory, a rule can select the
+// "bloom" or "sorted" strategy (see Exists), which streams the destination
+// and stays within the rule's memory limit.
 //
 // Calls log.Fatalf if any source value is not found in the destination,
 // or if required parameters are invalid.
@@ -28,75 +37,71 @@ func ExistsTest(stem string, ruler []Exists, metadata *Metadata) {
 	}
 	log.Printf("checking src file %s ...", stem)
 
-	// Validate metadata indices.
-	nameIndex := metadata.NameIndex
-	if nameIndex < 0 {
-		failRuntime(ValidationContext{File: fileName, Rule: "exists"}, "name_index [%d] is less than 0", nameIndex)
-		return
-	}
-	log.Printf("name_index: %d", nameIndex)
-
-	dataIndex := metadata.DataIndex
-	if dataIndex <= nameIndex {
-		failRuntime(ValidationContext{File: fileName, Rule: "exists"}, "data_index [%d] is less than or equal to name_index [%d]", dataIndex, nameIndex)
-		return
-	}
-	log.Printf("data_index: %d", dataIndex)
-
-	// Read the source CSV file and validate it has enough rows.
-	srcRecords := ReadCsvFile(stem, metadata)
-	if srcLen := len(srcRecords); srcLen <= dataIndex {
-		failRuntime(ValidationContext{File: fileName, Rule: "exists"}, "src_records length [%d] <= data_index [%d]", srcLen, dataIndex)
-		return
-	}
-	srcFields := srcRecords[nameIndex]
-	log.Printf("src_fields: %q", srcFields)
+	// Validate metadata indices and open the source CSV file.
+	source := requiredRecordSource(ValidationContext{File: fileName, Rule: "exists"}, stem, metadata)
+	log.Printf("src_fields: %q", source.table.Fields)
 
 	// Check each existence rule against its destination file.
 	for _, exist := range ruler {
 		dstFileName := csvFileName(exist.DstFileStem, metadata)
 
-		// Read the destination CSV file.
-		dstRecords := ReadCsvFile(exist.DstFileStem, metadata)
-		if dstLen := len(dstRecords); dstLen <= dataIndex {
-			failRuntime(
-				ValidationContext{File: dstFileName, Rule: "exists"},
-				"dst_records length [%d] <= data_index [%d]",
-				dstLen,
-				dataIndex,
-			)
+		// Resolve the lookup strategy and its memory limit.
+		switch exist.Strategy {
+		case "", "hash", "bloom", "sorted":
+		default:
+			failRuntime(ValidationContext{File: fileName, Rule: "exists"}, "strategy [%s] is not hash, bloom or sorted", exist.Strategy)
+			return
+		}
+		if exist.MemoryLimitMB < 0 {
+			failRuntime(ValidationContext{File: fileName, Rule: "exists"}, "memory_limit_mb [%d] is less than 0", exist.MemoryLimitMB)
 			return
 		}
-		log.Printf("checking dst file %s ...", exist.DstFileStem)
+		limit := int64(exist.MemoryLimitMB) << 20
+		streamed := exist.Strategy == "bloom" || exist.Strategy == "sorted"
+		if streamed && limit == 0 {
+			limit = defaultExistsMemoryLimitMB << 20
+		}
 
-		dstFields := dstRecords[nameIndex]
-		log.Printf("dst_fields: %q", dstFields)
+		// Open the destination CSV file on first use only; columns that are
+		// already indexed by an earlier rule or stem do not need it. The
+		// bloom and sorted strategies always stream it.
+		var dst *recordSource
+		loadDst := func() *recordSource {
+			if dst == nil {
+				log.Printf("checking dst file %s ...", exist.DstFileStem)
+				if streamed {
+					dst = requiredStreamSource(ValidationContext{File: dstFileName, Rule: "exists"}, exist.DstFileStem, metadata)
+				} else {
+					dst = requiredRecordSource(ValidationContext{File: dstFileName, Rule: "exists"}, exist.DstFileStem, metadata)
+				}
+				log.Printf("dst_fields: %q", dst.table.Fields)
+			}
+			return dst
+		}
 
 		// Validate each pair of source and destination fields.
 		for _, field := range exist.Fields {
+			srcCtx := ValidationContext{File: fileName, Rule: "exists", Field: field.Src}
+			dstCtx := ValidationContext{File: dstFileName, Rule: "exists", Field: field.Dst}
+			switch exist.Strategy {
+			case "bloom":
+				existsBloom(source, loadDst(), field, limit, srcCtx, dstCtx)
+				continue
+			case "sorted":
+				existsSorted(source, loadDst(), field, limit, srcCtx, dstCtx)
+				continue
+			}
+
 			// Create field expression for the source column.
 			srcFieldExpr := GenerateFieldExpr(metadata, field.Src)
-			srcFieldVals := requiredFieldOccurrences(
-				srcFieldExpr,
-				field.Src,
-				srcFields,
-				srcRecords,
-				ValidationContext{File: fileName, Rule: "exists", Field: field.Src},
-			)
+			srcFieldVals := source.occurrences(srcFieldExpr, field.Src, srcCtx)
 
-			// Create field expression for the destination column.
-			dstFieldExpr := GenerateFieldExpr(metadata, field.Dst)
-			dstFieldVals := requiredFieldOccurrences(
-				dstFieldExpr,
-				field.Dst,
-				dstFields,
-				dstRecords,
-				ValidationContext{File: dstFileName, Rule: "exists", Field: field.Dst},
-			)
+			// Index the destination column once per run.
+			dstIndex := requiredValueIndex(metadata, exist.DstFileStem, field.Dst, limit, loadDst, dstCtx)
 
-			// Track already-searched source values and cache destination values.
+			// Track already-searched source values.
 			searchedFields := make(map[string]int)
-			cacheDstFieldVals := make(map[string]bool)
+			stats := metadata.ruleStats()
 
 			for srcOccurrence := range srcFieldVals {
 				fieldVal := srcOccurrence.Value
@@ -104,40 +109,16 @@ func ExistsTest(stem string, ruler []Exists, metadata *Metadata) {
 				// Skip source values we've already verified.
 				if _, ok := searchedFields[fieldVal]; ok {
 					log.Printf("src_field [%s] value [%s] already searched at row [%d]", field.Src, fieldVal, searchedFields[fieldVal])
+					stats.cacheHit()
 					continue
 				}
 
-				// Check cache first before iterating destination values.
-				if _, ok := cacheDstFieldVals[fieldVal]; ok {
-					log.Printf("src_field [%s] value [%s] hit cache", field.Src, fieldVal)
-					continue
-				}
-
-				// Iterate through destination values until we find a match.
-				// Each consumed destination value is cached for future lookups.
-				for dstOccurrence := range dstFieldVals {
-					cacheDstFieldVals[dstOccurrence.Value] = true
-					if dstOccurrence.Value == fieldVal {
-						log.Printf("found src_field [%s] value [%s] in dst_records", field.Src, fieldVal)
-						break
-					}
-				}
-
-				// If the value was not found after exhausting destination values, fail.
-				if _, ok := cacheDstFieldVals[fieldVal]; !ok {
-					failValidation(
-						ValidationContext{
-							File:  fileName,
-							Rule:  "exists",
-							Field: field.Src,
-							Row:   rowPointer(srcOccurrence.Row),
-							Value: fieldVal,
-						},
-						"src_field [%s] value [%s] not found in dst_records",
-						field.Src,
-						fieldVal,
-					)
+				// Look the value up in the destination index.
+				dstRow, ok := dstIndex.lookup(fieldVal)
+				if !ok {
+					existsMissing(srcCtx, field, fieldVal, srcOccurrence.Row)
 				}
+				log.Printf("found src_field [%s] value [%s] in dst_records at row [%d]", field.Src, fieldVal, dstRow)
 
 				searchedFields[fieldVal] = srcOccurrence.Row
 			}
@@ -0,0 +1,145 @@
+package csvons
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileExistsTest validates that values of each field expression name files
+// that exist under the rule's base directory.
+//
+// For each value it:
+//  1. Substitutes the value into the rule's Template
+//  2. Checks the file extension against Extensions, if set
+//  3. Resolves every path component against the directory listing, with
+//     exact case unless CaseInsensitive is set, and requires a regular file;
+//     absolute paths and paths escaping the base directory are rejected
+//
+// Already-checked values and directory listings are cached per rule.
+func FileExistsTest(stem string, ruler []FileExists, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "file_exists"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "file_exists"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, rule := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "file_exists", Field: rule.Field}
+		baseDir := resolveRulerPath(rule.BaseDir, metadata)
+		template := rule.Template
+		if template == "" {
+			template = "{value}"
+		}
+
+		assets := newAssetTree(baseDir, rule.CaseInsensitive)
+		fieldExpr := GenerateFieldExpr(metadata, rule.Field)
+		checked := make(map[string]bool)
+		for occurrence := range src.occurrences(fieldExpr, rule.Field, ctx) {
+			fieldVal := occurrence.Value
+			if checked[fieldVal] {
+				continue
+			}
+			ctx.Row = rowPointer(occurrence.Row)
+			ctx.Value = fieldVal
+
+			if fieldVal == "" {
+				if rule.AllowEmpty {
+					continue
+				}
+				failValidation(ctx, "src_field [%s] value is empty", rule.Field)
+				return
+			}
+
+			relPath := strings.ReplaceAll(template, "{value}", fieldVal)
+			if rule.Extensions != nil && !slices.ContainsFunc(rule.Extensions, func(ext string) bool {
+				return strings.EqualFold(ext, filepath.Ext(relPath))
+			}) {
+				failValidation(ctx, "src_field [%s] path [%s] extension is not in %q", rule.Field, relPath, rule.Extensions)
+				return
+			}
+
+			if !assets.fileExists(relPath) {
+				failValidation(ctx, "src_field [%s] path [%s] not found in [%s]", rule.Field, relPath, baseDir)
+				return
+			}
+
+			checked[fieldVal] = true
+		}
+
+		log.Printf("src_field [%s] files exist", rule.Field)
+	}
+}
+
+// assetTree looks up asset paths under a base directory. Directory
+// listings are cached, so checking many values reads each directory once.
+type assetTree struct {
+	baseDir         string
+	caseInsensitive bool
+	listings        map[string]map[string]string // Directory → lookup key → entry name; empty if unreadable.
+}
+
+func newAssetTree(baseDir string, caseInsensitive bool) *assetTree {
+	if baseDir == "" {
+		baseDir = "."
+	}
+	return &assetTree{baseDir: baseDir, caseInsensitive: caseInsensitive, listings: make(map[string]map[string]string)}
+}
+
+// lookupKey returns the key under which a directory entry named name is
+// cached: the name itself, or its lower case in case-insensitive mode.
+func (t *assetTree) lookupKey(name string) string {
+	if t.caseInsensitive {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// listing returns the cached entries of dir, keyed by lookupKey. When two
+// entries share a key in case-insensitive mode, the first in sorted order wins.
+func (t *assetTree) listing(dir string) map[string]string {
+	names, ok := t.listings[dir]
+	if !ok {
+		entries, _ := os.ReadDir(dir)
+		names = make(map[string]string, len(entries))
+		for _, entry := range entries {
+			key := t.lookupKey(entry.Name())
+			if _, ok := names[key]; !ok {
+				names[key] = entry.Name()
+			}
+		}
+		t.listings[dir] = names
+	}
+	return names
+}
+
+// fileExists reports whether relPath names a regular file under the base
+// directory. Each component is looked up in its parent's directory listing
+// so that case is compared explicitly instead of relying on the host
+// filesystem.
+func (t *assetTree) fileExists(relPath string) bool {
+	relPath = filepath.Clean(filepath.FromSlash(relPath))
+	if filepath.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	current := t.baseDir
+	for _, part := range strings.Split(relPath, string(filepath.Separator)) {
+		name, ok := t.listing(current)[t.lookupKey(part)]
+		if !ok {
+			return false
+		}
+		current = filepath.Join(current, name)
+	}
+
+	info, err := os.Stat(current)
+	return err == nil && info.Mode().IsRegular()
+}
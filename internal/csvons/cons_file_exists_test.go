@@ -0,0 +1,75 @@
+package csvons
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFileExists verifies template resolution, case matching and extension whitelists.
+func TestFileExists(t *testing.T) {
+	dir := t.TempDir()
+	assets := filepath.Join(dir, "assets", "icons")
+	if err := os.MkdirAll(assets, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	for _, name := range []string{"sword.png", "Shield.png", "readme.txt"} {
+		if err := os.WriteFile(filepath.Join(assets, name), nil, 0o644); err != nil {
+			t.Fatalf("write asset failed: %v", err)
+		}
+	}
+
+	metadata := testMetadata(dir)
+	metadata.RulerDir = dir
+	rule := FileExists{Field: "Icon", BaseDir: "assets", Template: "icons/{value}.png"}
+
+	writeTestCsv(t, dir, "items", "Icon\nsword\nShield\nsword\n")
+	FileExistsTest("items", []FileExists{rule}, metadata)
+
+	writeTestCsv(t, dir, "items", "Icon\nsword\nshield\n")
+	err := expectValidationError(t, func() { FileExistsTest("items", []FileExists{rule}, metadata) })
+	if err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "path [icons/shield.png] not found") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	insensitive := rule
+	insensitive.CaseInsensitive = true
+	FileExistsTest("items", []FileExists{insensitive}, metadata)
+
+	writeTestCsv(t, dir, "items", "Icon\nicons/readme.txt\n")
+	whitelist := FileExists{Field: "Icon", BaseDir: "assets", Extensions: []string{".png"}}
+	err = expectValidationError(t, func() { FileExistsTest("items", []FileExists{whitelist}, metadata) })
+	if !strings.Contains(err.Message, "extension is not in") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	writeTestCsv(t, dir, "items", "Icon\n../items.csv\n")
+	err = expectValidationError(t, func() { FileExistsTest("items", []FileExists{{Field: "Icon", BaseDir: "assets"}}, metadata) })
+	if !strings.Contains(err.Message, "not found") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+// TestAssetTreeCachesListings verifies that each directory is listed once
+// however many paths are checked in it.
+func TestAssetTreeCachesListings(t *testing.T) {
+	dir := t.TempDir()
+	icons := filepath.Join(dir, "assets", "icons")
+	if err := os.MkdirAll(icons, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	for _, name := range []string{"sword.png", "shield.png"} {
+		if err := os.WriteFile(filepath.Join(icons, name), nil, 0o644); err != nil {
+			t.Fatalf("write asset failed: %v", err)
+		}
+	}
+
+	tree := newAssetTree(dir, false)
+	for _, path := range []string{"assets/icons/sword.png", "assets/icons/shield.png", "assets/icons/bow.png"} {
+		tree.fileExists(path)
+	}
+	if len(tree.listings) != 3 {
+		t.Fatalf("listed %d directories, expected 3: %v", len(tree.listings), tree.listings)
+	}
+}
@@ -0,0 +1,265 @@
+package csvons
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+// GraphTest validates reference graphs between rows of a CSV file.
+//
+// For each rule it builds a graph with one node per row (from Node) and
+// parent-to-child edges (from Edges, according to EdgeType), then checks:
+//  1. Every referenced node exists (always)
+//  2. There are no cycles, if Acyclic or MaxDepth is set; the cycle path is reported
+//  3. Exactly one node has no parent, if SingleRoot is set
+//  4. No node is deeper than MaxDepth below a root, if set
+//  5. Every node is reachable from the Start nodes, if set
+func GraphTest(stem string, ruler []Graph, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "graph"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "graph"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, rule := range ruler {
+		graph := buildRowGraph(fileName, rule, src, metadata)
+		ctx := ValidationContext{File: fileName, Rule: "graph", Field: rule.Node}
+
+		if rule.Acyclic || rule.MaxDepth > 0 {
+			if cycle := graph.findCycle(); cycle != nil {
+				ctx.Row = rowPointer(graph.rows[cycle[0]])
+				ctx.Value = cycle[0]
+				failValidation(ctx, "src_field [%s] has a cycle: %s", rule.Node, strings.Join(cycle, " -> "))
+				return
+			}
+		}
+
+		roots := graph.roots()
+		if rule.SingleRoot && len(roots) != 1 {
+			ctx.Value = strings.Join(roots, ",")
+			failValidation(ctx, "src_field [%s] has [%d] roots %q, expected exactly one", rule.Node, len(roots), roots)
+			return
+		}
+
+		if rule.MaxDepth > 0 {
+			depths := graph.depths(roots)
+			for _, node := range graph.nodes {
+				if depths[node] > rule.MaxDepth {
+					ctx.Row = rowPointer(graph.rows[node])
+					ctx.Value = node
+					failValidation(ctx, "src_field [%s] node [%s] depth [%d] exceeds max_depth [%d]", rule.Node, node, depths[node], rule.MaxDepth)
+					return
+				}
+			}
+		}
+
+		if rule.Start != nil {
+			for _, start := range rule.Start {
+				if _, ok := graph.rows[start]; !ok {
+					failRuntime(ctx, "src_field [%s] start node [%s] not found", rule.Node, start)
+					return
+				}
+			}
+			reached := graph.reachable(rule.Start)
+			for _, node := range graph.nodes {
+				if !reached[node] {
+					ctx.Row = rowPointer(graph.rows[node])
+					ctx.Value = node
+					failValidation(ctx, "src_field [%s] node [%s] is not reachable from %q", rule.Node, node, rule.Start)
+					return
+				}
+			}
+		}
+
+		log.Printf("src_field [%s] graph is valid", rule.Node)
+	}
+}
+
+// rowGraph is a directed graph of row nodes with parent-to-child edges.
+type rowGraph struct {
+	nodes    []string            // Node IDs in sorted order.
+	rows     map[string]int      // Node ID → 1-based row number.
+	children map[string][]string // Node ID → child node IDs, in sorted order.
+	parents  map[string]int      // Node ID → number of parents.
+
+	ctx      ValidationContext
+	metadata *Metadata
+	steps    int // Nodes visited by graph walks, for cancellation checks.
+}
+
+// step counts one node visited by a graph walk and aborts via failRuntime
+// every cancelCheckInterval visits if the run is cancelled.
+func (g *rowGraph) step() {
+	if g.steps%cancelCheckInterval == 0 {
+		checkCancelled(g.ctx, g.metadata)
+	}
+	g.steps++
+}
+
+// buildRowGraph reads the node and edge expressions into a rowGraph.
+// It aborts on duplicate node IDs and on references to unknown nodes.
+func buildRowGraph(fileName string, rule Graph, source *recordSource, metadata *Metadata) *rowGraph {
+	ctx := ValidationContext{File: fileName, Rule: "graph", Field: rule.Node}
+	if rule.EdgeType != "" && rule.EdgeType != "parent" && rule.EdgeType != "child" {
+		failRuntime(ctx, "src_field [%s] edge_type [%s] is not parent or child", rule.Node, rule.EdgeType)
+		return nil
+	}
+
+	graph := &rowGraph{rows: map[string]int{}, children: map[string][]string{}, parents: map[string]int{}, ctx: ctx, metadata: metadata}
+	rowNodes := make(map[int]string)
+	nodeExpr := GenerateFieldExpr(metadata, rule.Node)
+	for occurrence := range source.occurrences(nodeExpr, rule.Node, ctx) {
+		if row, ok := graph.rows[occurrence.Value]; ok {
+			ctx.Row = rowPointer(occurrence.Row)
+			ctx.Value = occurrence.Value
+			failValidation(ctx, "src_field [%s] node [%s] is already defined at row [%d]", rule.Node, occurrence.Value, row)
+			return nil
+		}
+		graph.rows[occurrence.Value] = occurrence.Row
+		graph.nodes = append(graph.nodes, occurrence.Value)
+		rowNodes[occurrence.Row] = occurrence.Value
+	}
+	slices.Sort(graph.nodes)
+
+	for _, edge := range rule.Edges {
+		edgeCtx := ValidationContext{File: fileName, Rule: "graph", Field: edge}
+		edgeExpr := GenerateFieldExpr(metadata, edge)
+		for occurrence := range source.occurrences(edgeExpr, edge, edgeCtx) {
+			node, ok := rowNodes[occurrence.Row]
+			if !ok || occurrence.Value == "" {
+				continue
+			}
+			if _, ok := graph.rows[occurrence.Value]; !ok {
+				edgeCtx.Row = rowPointer(occurrence.Row)
+				edgeCtx.Value = occurrence.Value
+				failValidation(edgeCtx, "src_field [%s] value [%s] references an unknown node", edge, occurrence.Value)
+				return nil
+			}
+
+			parent, child := occurrence.Value, node
+			if rule.EdgeType == "child" {
+				parent, child = node, occurrence.Value
+			}
+			graph.children[parent] = append(graph.children[parent], child)
+			graph.parents[child]++
+		}
+	}
+	for _, children := range graph.children {
+		slices.Sort(children)
+	}
+	return graph
+}
+
+// roots returns the nodes without a parent, in sorted order.
+func (g *rowGraph) roots() []string {
+	var roots []string
+	for _, node := range g.nodes {
+		if g.parents[node] == 0 {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// findCycle returns a cycle as a node path that starts and ends with the
+// same node, or nil if the graph is acyclic. The depth-first search keeps
+// its own stack so that long chains do not grow the goroutine stack.
+func (g *rowGraph) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	type frame struct {
+		node string
+		next int // Index of the next child to visit.
+	}
+	state := make(map[string]int, len(g.nodes))
+	var stack []frame
+
+	for _, root := range g.nodes {
+		if state[root] != unvisited {
+			continue
+		}
+		g.step()
+		state[root] = visiting
+		stack = append(stack, frame{node: root})
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			children := g.children[top.node]
+			if top.next == len(children) {
+				state[top.node] = done
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			child := children[top.next]
+			top.next++
+			switch state[child] {
+			case visiting:
+				start := slices.IndexFunc(stack, func(f frame) bool { return f.node == child })
+				cycle := make([]string, 0, len(stack)-start+1)
+				for _, f := range stack[start:] {
+					cycle = append(cycle, f.node)
+				}
+				return append(cycle, child)
+			case unvisited:
+				g.step()
+				state[child] = visiting
+				stack = append(stack, frame{node: child})
+			}
+		}
+	}
+	return nil
+}
+
+// depths returns the longest distance from any root to each node, visiting
+// the nodes in topological order (Kahn's algorithm) so that every edge is
+// relaxed once. The graph must be acyclic.
+func (g *rowGraph) depths(roots []string) map[string]int {
+	depths := make(map[string]int, len(g.nodes))
+	remaining := make(map[string]int, len(g.parents))
+	for node, count := range g.parents {
+		remaining[node] = count
+	}
+	queue := slices.Clone(roots)
+	for _, root := range roots {
+		depths[root] = 0
+	}
+	for len(queue) > 0 {
+		g.step()
+		node := queue[0]
+		queue = queue[1:]
+		for _, child := range g.children[node] {
+			depths[child] = max(depths[child], depths[node]+1)
+			remaining[child]--
+			if remaining[child] == 0 {
+				queue = append(queue, child)
+			}
+		}
+	}
+	return depths
+}
+
+// reachable returns the set of nodes reachable from the start nodes.
+func (g *rowGraph) reachable(start []string) map[string]bool {
+	reached := make(map[string]bool, len(g.nodes))
+	queue := slices.Clone(start)
+	for len(queue) > 0 {
+		g.step()
+		node := queue[0]
+		queue = queue[1:]
+		if reached[node] {
+			continue
+		}
+		reached[node] = true
+		queue = append(queue, g.children[node]...)
+	}
+	return reached
+}
@@ -0,0 +1,120 @@
+package csvons
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGraphQuests validates graph constraints using the quests test data.
+// Tests a parent tree and a multi-edge unlock graph.
+func TestGraphQuests(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_quests.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "graph" {
+				var graph []Graph
+				if err := json.Unmarshal(v, &graph); err != nil {
+					t.Fatalf("error unmarshalling graph: %v", err)
+				}
+				GraphTest(stem, graph, metadata)
+			}
+		}
+	}
+}
+
+// TestGraphRejects verifies cycle, root, depth, reachability and dangling reference checks.
+func TestGraphRejects(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		rule    Graph
+		message string
+	}{
+		{
+			"cycle",
+			"ID,Parent\nA,\nB,D\nC,B\nD,C\n",
+			Graph{Node: "ID", Edges: []string{"Parent"}, Acyclic: true},
+			"has a cycle: B -> C -> D -> B",
+		},
+		{
+			"two roots",
+			"ID,Parent\nA,\nB,\nC,A\n",
+			Graph{Node: "ID", Edges: []string{"Parent"}, SingleRoot: true},
+			`has [2] roots ["A" "B"]`,
+		},
+		{
+			"max depth",
+			"ID,Parent\nA,\nB,A\nC,B\n",
+			Graph{Node: "ID", Edges: []string{"Parent"}, MaxDepth: 1},
+			"node [C] depth [2] exceeds max_depth [1]",
+		},
+		{
+			"unreachable",
+			"ID,Next\nA,B\nB,\nC,\n",
+			Graph{Node: "ID", Edges: []string{"Next[]"}, EdgeType: "child", Start: []string{"A"}},
+			"node [C] is not reachable",
+		},
+		{
+			"unknown node",
+			"ID,Parent\nA,\nB,Z\n",
+			Graph{Node: "ID", Edges: []string{"Parent"}},
+			"value [Z] references an unknown node",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestCsv(t, dir, "nodes", tt.content)
+			err := expectValidationError(t, func() { GraphTest("nodes", []Graph{tt.rule}, metadata) })
+			if !strings.Contains(err.Message, tt.message) {
+				t.Fatalf("unexpected message: %q", err.Message)
+			}
+		})
+	}
+}
+
+// TestGraphDepthLadder verifies that depths are computed once per edge: a
+// ladder of layers where each node has both nodes of the layer above as
+// parents has 2^layers root-to-leaf paths.
+func TestGraphDepthLadder(t *testing.T) {
+	dir := t.TempDir()
+	const layers = 64
+	var b strings.Builder
+	b.WriteString("ID,Parents\nR,\n")
+	for layer := 1; layer <= layers; layer++ {
+		parents := "R"
+		if layer > 1 {
+			parents = fmt.Sprintf("a%d;b%d", layer-1, layer-1)
+		}
+		fmt.Fprintf(&b, "a%d,%s\nb%d,%s\n", layer, parents, layer, parents)
+	}
+	writeTestCsv(t, dir, "ladder", b.String())
+	metadata := testMetadata(dir)
+
+	GraphTest("ladder", []Graph{{Node: "ID", Edges: []string{"Parents[]"}, MaxDepth: layers}}, metadata)
+	err := expectValidationError(t, func() {
+		GraphTest("ladder", []Graph{{Node: "ID", Edges: []string{"Parents[]"}, MaxDepth: layers - 1}}, metadata)
+	})
+	if want := fmt.Sprintf("depth [%d] exceeds max_depth [%d]", layers, layers-1); !strings.Contains(err.Message, want) {
+		t.Fatalf("unexpected message: %q", err.Message)
+	}
+}
@@ -0,0 +1,159 @@
+package csvons
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+)
+
+// placeholderPattern matches format placeholders that must survive
+// translation: positional "{0}", named "{name}" and printf-style "%s", "%05.2f".
+// The printf space flag is not accepted, so that prose such as "50% sale"
+// is not read as the placeholder "% s".
+var placeholderPattern = regexp.MustCompile(`\{\d+\}|\{[a-zA-Z_][a-zA-Z0-9_]*\}|%[-+#0]*\d*(?:\.\d+)?[sdfgvqxXc]`)
+
+// I18nTest validates localization completeness of a strings table.
+//
+// For every data row with a non-empty source text and each required locale
+// (a column in Locales, or a per-locale file in Files joined on the key, as
+// ExistsTest does), it checks that:
+//  1. The key has a translation, and the translation is not blank
+//  2. The translation uses the same placeholders as the source, if Placeholders is set
+//  3. The translation is at most MaxLengthRatio times as long as the source, if set
+func I18nTest(stem string, ruler []I18n, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "i18n"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "i18n"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, i18n := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "i18n", Field: i18n.Source}
+		keys := src.rowValues(i18n.Key, ctx)
+		sources := src.rowValues(i18n.Source, ctx)
+
+		// Resolve each locale's translations by source row.
+		locales := make([]string, 0, len(i18n.Locales)+len(i18n.Files))
+		translations := make(map[string]map[int]string)
+		for _, locale := range i18n.Locales {
+			locales = append(locales, locale)
+			translations[locale] = src.rowValues(locale, ctx)
+		}
+		for _, localeFile := range i18n.Files {
+			locales = append(locales, localeFile.Locale)
+			translations[localeFile.Locale] = joinLocaleFile(localeFile, keys, metadata)
+		}
+
+		rows := make([]int, 0, len(sources))
+		for row := range sources {
+			rows = append(rows, row)
+		}
+		slices.Sort(rows)
+
+		for _, row := range rows {
+			source := sources[row]
+			if source == "" {
+				continue
+			}
+			key := keys[row]
+			ctx.Row = rowPointer(row)
+
+			for _, locale := range locales {
+				text, ok := translations[locale][row]
+				ctx.Field = locale
+				ctx.Value = text
+				if !ok {
+					failValidation(ctx, "key [%s] has no [%s] translation", key, locale)
+					return
+				}
+				if isBlankRecord([]string{text}) {
+					failValidation(ctx, "key [%s] [%s] translation is empty", key, locale)
+					return
+				}
+				if i18n.Placeholders && !samePlaceholders(source, text) {
+					failValidation(
+						ctx,
+						"key [%s] [%s] translation placeholders %q do not match source %q",
+						key,
+						locale,
+						sortedPlaceholders(text),
+						sortedPlaceholders(source),
+					)
+					return
+				}
+				if i18n.MaxLengthRatio > 0 {
+					sourceLen, textLen := utf8.RuneCountInString(source), utf8.RuneCountInString(text)
+					if float64(textLen) > i18n.MaxLengthRatio*float64(sourceLen) {
+						failValidation(ctx, "key [%s] [%s] translation length [%d] exceeds [%v] x source length [%d]", key, locale, textLen, i18n.MaxLengthRatio, sourceLen)
+						return
+					}
+				}
+			}
+		}
+
+		log.Printf("src_field [%s] translations are complete", i18n.Source)
+	}
+}
+
+// joinLocaleFile reads a per-locale file and maps each source row to the
+// translation whose key matches the source row's key. Keys must be unique
+// in the locale file.
+func joinLocaleFile(localeFile LocaleFile, keys map[int]string, metadata *Metadata) map[int]string {
+	dstFileName := csvFileName(localeFile.DstFileStem, metadata)
+	ctx := ValidationContext{File: dstFileName, Rule: "i18n", Field: localeFile.Text}
+	dst := requiredRecordSource(ctx, localeFile.DstFileStem, metadata)
+
+	dstKeys := dst.rowValues(localeFile.Key, ctx)
+	dstTexts := dst.rowValues(localeFile.Text, ctx)
+	rows := make([]int, 0, len(dstKeys))
+	for row := range dstKeys {
+		rows = append(rows, row)
+	}
+	slices.Sort(rows)
+
+	// A key must name one translation; a second one would make the checked
+	// text depend on which row wins.
+	textByKey := make(map[string]string, len(dstKeys))
+	keyRows := make(map[string]int, len(dstKeys))
+	for _, row := range rows {
+		key := dstKeys[row]
+		if firstRow, ok := keyRows[key]; ok {
+			ctx.Field = localeFile.Key
+			ctx.Row = rowPointer(row)
+			ctx.Value = key
+			failValidation(ctx, "key [%s] is duplicated; first defined at row [%d]", key, firstRow)
+			return nil
+		}
+		keyRows[key] = row
+		if text, ok := dstTexts[row]; ok {
+			textByKey[key] = text
+		}
+	}
+
+	translations := make(map[int]string, len(keys))
+	for row, key := range keys {
+		if text, ok := textByKey[key]; ok {
+			translations[row] = text
+		}
+	}
+	return translations
+}
+
+// sortedPlaceholders returns the placeholders of s in sorted order.
+func sortedPlaceholders(s string) []string {
+	placeholders := placeholderPattern.FindAllString(s, -1)
+	slices.Sort(placeholders)
+	return placeholders
+}
+
+// samePlaceholders reports whether a and b use the same multiset of placeholders.
+func samePlaceholders(a, b string) bool {
+	return slices.Equal(sortedPlaceholders(a), sortedPlaceholders(b))
+}
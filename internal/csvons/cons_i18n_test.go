@@ -0,0 +1,122 @@
+package csvons
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestI18nStrings validates i18n constraints using the strings test data.
+// Tests locale columns, a per-locale file, placeholders and the length ratio.
+func TestI18nStrings(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_strings.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "i18n" {
+				var i18n []I18n
+				if err := json.Unmarshal(v, &i18n); err != nil {
+					t.Fatalf("error unmarshalling i18n: %v", err)
+				}
+				I18nTest(stem, i18n, metadata)
+			}
+		}
+	}
+}
+
+// TestI18nRejects verifies missing, empty, placeholder and length violations.
+func TestI18nRejects(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+	writeTestCsv(t, dir, "strings", "Key,en,zh\nok,Hi {0},嗨 {0}\nbad,Hi {0},\" \"\nph,Got %d,得到 %s\nlong,Go,走走走走走\n")
+	writeTestCsv(t, dir, "strings_ko", "Key,Text\nok,안녕 {0}\n")
+
+	tests := []struct {
+		name    string
+		rule    I18n
+		row     int
+		message string
+	}{
+		{"empty", I18n{Key: "Key", Source: "en", Locales: []string{"zh"}}, 3, "key [bad] [zh] translation is empty"},
+		{"missing in file", I18n{Key: "Key", Source: "en", Files: []LocaleFile{{Locale: "ko", DstFileStem: "strings_ko", Key: "Key", Text: "Text"}}}, 3, "key [bad] has no [ko] translation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := expectValidationError(t, func() { I18nTest("strings", []I18n{tt.rule}, metadata) })
+			if err.Row == nil || *err.Row != tt.row {
+				t.Fatalf("unexpected row: %#v", err.Row)
+			}
+			if !strings.Contains(err.Message, tt.message) {
+				t.Fatalf("unexpected message: %q", err.Message)
+			}
+		})
+	}
+
+	writeTestCsv(t, dir, "strings", "Key,en,zh\nok,Hi {0},嗨 {0}\nph,Got %d,得到 %s\n")
+	err := expectValidationError(t, func() {
+		I18nTest("strings", []I18n{{Key: "Key", Source: "en", Locales: []string{"zh"}, Placeholders: true}}, metadata)
+	})
+	if err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "placeholders") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	writeTestCsv(t, dir, "strings", "Key,en,zh\nok,Hi {0},嗨 {0}\nlong,Go,走走走走走\n")
+	err = expectValidationError(t, func() {
+		I18nTest("strings", []I18n{{Key: "Key", Source: "en", Locales: []string{"zh"}, MaxLengthRatio: 2}}, metadata)
+	})
+	if err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "exceeds [2] x source length [2]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+// TestI18nRejectsDuplicateLocaleKeys verifies that a key defined twice in a
+// locale file is reported instead of checking whichever translation wins.
+func TestI18nRejectsDuplicateLocaleKeys(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+	writeTestCsv(t, dir, "strings", "Key,en\nok,Hi {0}\n")
+	writeTestCsv(t, dir, "strings_ko", "Key,Text\nok,안녕 {0}\nother,x\nok,안녕\n")
+
+	rule := I18n{Key: "Key", Source: "en", Files: []LocaleFile{{Locale: "ko", DstFileStem: "strings_ko", Key: "Key", Text: "Text"}}}
+	err := expectValidationError(t, func() { I18nTest("strings", []I18n{rule}, metadata) })
+	if err.File != "strings_ko.csv" || err.Row == nil || *err.Row != 4 || err.Value != "ok" {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !strings.Contains(err.Message, "key [ok] is duplicated; first defined at row [2]") {
+		t.Fatalf("unexpected message: %q", err.Message)
+	}
+}
+
+// TestSortedPlaceholders verifies which substrings count as placeholders.
+func TestSortedPlaceholders(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hi {name}, you have {0} items", []string{"{0}", "{name}"}},
+		{"Deal %-5d of %05.2f", []string{"%-5d", "%05.2f"}},
+		{"50% sale", nil},
+		{"100% done, %s left", []string{"%s"}},
+	}
+
+	for _, tt := range tests {
+		if got := sortedPlaceholders(tt.text); !slices.Equal(got, tt.want) {
+			t.Fatalf("%q: got %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package csvons
+
+import (
+	"log"
+	"slices"
+	"strings"
+)
+
+// comparisonOps maps each LookupCompare operator to its test on the result
+// of compareTyped.
+var comparisonOps = map[string]func(int) bool{
+	"==": func(c int) bool { return c == 0 },
+	"!=": func(c int) bool { return c != 0 },
+	"<":  func(c int) bool { return c < 0 },
+	"<=": func(c int) bool { return c <= 0 },
+	">":  func(c int) bool { return c > 0 },
+	">=": func(c int) bool { return c >= 0 },
+}
+
+// LookupCompareTest validates values of a source file against the joined
+// row of a destination file.
+//
+// For each rule it:
+//  1. Indexes the destination rows by the values of the Keys dst expressions
+//  2. Joins every source row on the Keys src expressions; a missing match
+//     fails unless AllowMissing is set, and an ambiguous match always fails
+//  3. Compares each Compare src value with the dst value of the joined row;
+//     a src or dst expression without a value in its row fails
+//
+// Failures report both the source row and the destination row.
+func LookupCompareTest(stem string, ruler []LookupCompare, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "lookup_compare"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "lookup_compare"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, lookup := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "lookup_compare"}
+		if len(lookup.Keys) == 0 {
+			failRuntime(ctx, "keys of dst file [%s] are empty", lookup.DstFileStem)
+			return
+		}
+		for _, comparison := range lookup.Compare {
+			if _, ok := comparisonOps[comparison.Op]; !ok {
+				ctx.Field = comparison.Src
+				failRuntime(ctx, "src_field [%s] op [%s] is not a valid operator", comparison.Src, comparison.Op)
+				return
+			}
+		}
+
+		dstFileName := csvFileName(lookup.DstFileStem, metadata)
+		dstCtx := ValidationContext{File: dstFileName, Rule: "lookup_compare"}
+		dst := requiredRecordSource(dstCtx, lookup.DstFileStem, metadata)
+		log.Printf("checking dst file %s ...", lookup.DstFileStem)
+
+		// Index destination rows by their joined key.
+		dstKeys := joinKeys(lookup.Keys, func(pair FieldPair) map[int]string {
+			return dst.rowValues(pair.Dst, dstCtx)
+		}, metadata)
+		dstRowsByKey := make(map[string][]int, len(dstKeys))
+		for row, key := range dstKeys {
+			dstRowsByKey[key] = append(dstRowsByKey[key], row)
+		}
+
+		srcKeys := joinKeys(lookup.Keys, func(pair FieldPair) map[int]string {
+			return src.rowValues(pair.Src, ctx)
+		}, metadata)
+
+		srcValues := make([]map[int]string, len(lookup.Compare))
+		dstValues := make([]map[int]string, len(lookup.Compare))
+		for i, comparison := range lookup.Compare {
+			srcValues[i] = src.rowValues(comparison.Src, ctx)
+			dstValues[i] = dst.rowValues(comparison.Dst, dstCtx)
+		}
+
+		rows := make([]int, 0, len(srcKeys))
+		for row := range srcKeys {
+			rows = append(rows, row)
+		}
+		slices.Sort(rows)
+
+		for _, row := range rows {
+			key := srcKeys[row]
+			ctx.Row = rowPointer(row)
+			ctx.Field = lookup.Keys[0].Src
+			ctx.Value = key
+
+			dstRows := dstRowsByKey[key]
+			if len(dstRows) == 0 {
+				if lookup.AllowMissing {
+					continue
+				}
+				failValidation(ctx, "row [%d] key [%s] not found in dst file [%s]", row, key, dstFileName)
+				return
+			}
+			if len(dstRows) > 1 {
+				slices.Sort(dstRows)
+				failValidation(ctx, "row [%d] key [%s] matches rows %v of dst file [%s]", row, key, dstRows, dstFileName)
+				return
+			}
+			dstRow := dstRows[0]
+
+			for i, comparison := range lookup.Compare {
+				srcVal, srcOK := srcValues[i][row]
+				dstVal, dstOK := dstValues[i][dstRow]
+				ctx.Field = comparison.Src
+				ctx.Value = srcVal
+				if !srcOK {
+					failValidation(ctx, "src_field [%s] has no value at row [%d]", comparison.Src, row)
+					return
+				}
+				if !dstOK {
+					failValidation(ctx, "dst_field [%s] has no value at row [%d] of [%s] joined by row [%d]", comparison.Dst, dstRow, dstFileName, row)
+					return
+				}
+
+				order, err := compareTyped(srcVal, dstVal, comparison.Type)
+				if err != nil {
+					failValidation(ctx, "src_field [%s] at row [%d] vs dst_field [%s] at row [%d] of [%s]: %v", comparison.Src, row, comparison.Dst, dstRow, dstFileName, err)
+					return
+				}
+				if !comparisonOps[comparison.Op](order) {
+					failValidation(
+						ctx,
+						"src_field [%s] value [%s] at row [%d] is not %s dst_field [%s] value [%s] at row [%d] of [%s]",
+						comparison.Src,
+						srcVal,
+						row,
+						comparison.Op,
+						comparison.Dst,
+						dstVal,
+						dstRow,
+						dstFileName,
+					)
+					return
+				}
+			}
+		}
+
+		log.Printf("lookup_compare against dst file %s is valid", lookup.DstFileStem)
+	}
+}
+
+// joinKeys resolves each key pair with rowValues and joins the per-row
+// values with FieldConnector. Rows missing any key value are left out.
+func joinKeys(keys []FieldPair, rowValues func(FieldPair) map[int]string, metadata *Metadata) map[int]string {
+	resolved := make([]map[int]string, len(keys))
+	for i, pair := range keys {
+		resolved[i] = rowValues(pair)
+	}
+
+	joined := make(map[int]string, len(resolved[0]))
+rows:
+	for row := range resolved[0] {
+		parts := make([]string, len(resolved))
+		for i, values := range resolved {
+			value, ok := values[row]
+			if !ok {
+				continue rows
+			}
+			parts[i] = value
+		}
+		joined[row] = strings.Join(parts, metadata.FieldConnector)
+	}
+	return joined
+}
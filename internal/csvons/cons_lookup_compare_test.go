@@ -0,0 +1,127 @@
+package csvons
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLookupCompareProducts validates lookup_compare constraints using the products test data.
+// Shop prices must not be below the product price.
+func TestLookupCompareProducts(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_products.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "lookup_compare" {
+				var lookups []LookupCompare
+				if err := json.Unmarshal(v, &lookups); err != nil {
+					t.Fatalf("error unmarshalling lookup_compare: %v", err)
+				}
+				LookupCompareTest(stem, lookups, metadata)
+			}
+		}
+	}
+}
+
+// TestLookupCompareRejects verifies comparison failures, missing and ambiguous joins.
+func TestLookupCompareRejects(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+	writeTestCsv(t, dir, "items", "ID,Sell\na,10\nb,20\nb,25\n")
+
+	compare := []Comparison{{Src: "Price", Op: ">=", Dst: "Sell", Type: "int"}}
+	keys := []FieldPair{{Src: "ItemID", Dst: "ID"}}
+
+	tests := []struct {
+		name    string
+		content string
+		rule    LookupCompare
+		row     int
+		message string
+	}{
+		{
+			"comparison",
+			"ItemID,Price\na,12\na,9\n",
+			LookupCompare{DstFileStem: "items", Keys: keys, Compare: compare},
+			3,
+			"value [9] at row [3] is not >= dst_field [Sell] value [10] at row [2]",
+		},
+		{
+			"missing",
+			"ItemID,Price\nz,12\n",
+			LookupCompare{DstFileStem: "items", Keys: keys, Compare: compare},
+			2,
+			"key [z] not found",
+		},
+		{
+			"ambiguous",
+			"ItemID,Price\nb,30\n",
+			LookupCompare{DstFileStem: "items", Keys: keys, Compare: compare},
+			2,
+			"matches rows [3 4]",
+		},
+		{
+			"multi-valued key",
+			"ItemID,Price\n\"a;b\",12\n",
+			LookupCompare{DstFileStem: "items", Keys: []FieldPair{{Src: "ItemID[]", Dst: "ID"}}, Compare: compare},
+			2,
+			"field expression [ItemID[]] yields more than one value in a row",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestCsv(t, dir, "shop", tt.content)
+			err := expectValidationError(t, func() { LookupCompareTest("shop", []LookupCompare{tt.rule}, metadata) })
+			if err.Row == nil || *err.Row != tt.row {
+				t.Fatalf("unexpected row: %#v", err.Row)
+			}
+			if !strings.Contains(err.Message, tt.message) {
+				t.Fatalf("unexpected message: %q", err.Message)
+			}
+		})
+	}
+
+	writeTestCsv(t, dir, "shop", "ItemID,Price\na,10\nz,1\n")
+	LookupCompareTest("shop", []LookupCompare{{DstFileStem: "items", Keys: keys, Compare: compare, AllowMissing: true}}, metadata)
+}
+
+// TestLookupCompareRejectsMissingValues verifies that a compare expression
+// without a value in the joined row fails instead of comparing "".
+func TestLookupCompareRejectsMissingValues(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+	writeTestCsv(t, dir, "items", "ID,Stats\na,sell=10\nb,buy=5\n")
+	keys := []FieldPair{{Src: "ItemID", Dst: "ID"}}
+
+	writeTestCsv(t, dir, "shop", "ItemID,Stats\na,price=12\nb,price=6\n")
+	compare := []Comparison{{Src: "Stats[price]", Op: ">=", Dst: "Stats[sell]", Type: "int"}}
+	err := expectValidationError(t, func() {
+		LookupCompareTest("shop", []LookupCompare{{DstFileStem: "items", Keys: keys, Compare: compare}}, metadata)
+	})
+	if err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "dst_field [Stats[sell]] has no value at row [3]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	writeTestCsv(t, dir, "shop", "ItemID,Stats\na,cost=12\n")
+	err = expectValidationError(t, func() {
+		LookupCompareTest("shop", []LookupCompare{{DstFileStem: "items", Keys: keys, Compare: compare}}, metadata)
+	})
+	if err.Row == nil || *err.Row != 2 || !strings.Contains(err.Message, "src_field [Stats[price]] has no value at row [2]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
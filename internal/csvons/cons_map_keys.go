@@ -0,0 +1,96 @@
+package csvons
+
+import (
+	"log"
+	"slices"
+)
+
+// MapKeysTest validates the key sets of key-value map cells row by row.
+//
+// Each cell of Field is split into entries (see splitMapCell). For every row:
+//  1. Every entry must contain the map separator
+//  2. Each Required key must be present
+//  3. No Forbidden key may be present
+//  4. If Allowed is set, every key must be listed in it
+//  5. If KeysFrom is set, every key must exist in the named column of another file
+//
+// Duplicate keys within one cell are also rejected.
+func MapKeysTest(stem string, ruler []MapKeys, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "map_keys"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "map_keys"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, mapKeys := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "map_keys", Field: mapKeys.Field}
+
+		var knownKeys map[string]bool
+		if mapKeys.KeysFrom != nil {
+			knownKeys = requiredKeySet(mapKeys.KeysFrom, metadata)
+		}
+
+		fieldExpr := &PlainField{}
+		fieldExpr.Init(metadata, mapKeys.Field)
+		for occurrence := range src.occurrences(fieldExpr, mapKeys.Field, ctx) {
+			ctx.Row = rowPointer(occurrence.Row)
+			ctx.Value = occurrence.Value
+
+			keys := make(map[string]bool)
+			for _, entry := range splitMapCell(occurrence.Value, metadata) {
+				if !entry.ok {
+					failValidation(ctx, "src_field [%s] entry [%s] is not a key-value pair", mapKeys.Field, entry.key)
+					return
+				}
+				if keys[entry.key] {
+					failValidation(ctx, "src_field [%s] key [%s] is duplicated", mapKeys.Field, entry.key)
+					return
+				}
+				keys[entry.key] = true
+
+				if slices.Contains(mapKeys.Forbidden, entry.key) {
+					failValidation(ctx, "src_field [%s] key [%s] is forbidden", mapKeys.Field, entry.key)
+					return
+				}
+				if mapKeys.Allowed != nil && !slices.Contains(mapKeys.Allowed, entry.key) {
+					failValidation(ctx, "src_field [%s] key [%s] is not in allowed keys %q", mapKeys.Field, entry.key, mapKeys.Allowed)
+					return
+				}
+				if knownKeys != nil && !knownKeys[entry.key] {
+					failValidation(ctx, "src_field [%s] key [%s] not found in dst_file [%s]", mapKeys.Field, entry.key, mapKeys.KeysFrom.DstFileStem)
+					return
+				}
+			}
+
+			for _, required := range mapKeys.Required {
+				if !keys[required] {
+					failValidation(ctx, "src_field [%s] required key [%s] is missing", mapKeys.Field, required)
+					return
+				}
+			}
+		}
+
+		log.Printf("src_field [%s] map keys are valid", mapKeys.Field)
+	}
+}
+
+// requiredKeySet reads the key column named by source into a set.
+// It aborts via failRuntime if the file or column cannot be read.
+func requiredKeySet(source *KeysSource, metadata *Metadata) map[string]bool {
+	dstFileName := csvFileName(source.DstFileStem, metadata)
+	ctx := ValidationContext{File: dstFileName, Rule: "map_keys", Field: source.Field}
+	dst := requiredRecordSource(ctx, source.DstFileStem, metadata)
+
+	keys := make(map[string]bool)
+	dstFieldExpr := GenerateFieldExpr(metadata, source.Field)
+	for occurrence := range dst.occurrences(dstFieldExpr, source.Field, ctx) {
+		keys[occurrence.Value] = true
+	}
+	return keys
+}
@@ -0,0 +1,82 @@
+package csvons
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMapKeysHeroes validates map_keys and map field vtype constraints using the heroes test data.
+func TestMapKeysHeroes(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_heroes.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			switch k {
+			case "map_keys":
+				var mapKeys []MapKeys
+				if err := json.Unmarshal(v, &mapKeys); err != nil {
+					t.Fatalf("error unmarshalling map_keys: %v", err)
+				}
+				MapKeysTest(stem, mapKeys, metadata)
+			case "vtype":
+				var vtype []VType
+				if err := json.Unmarshal(v, &vtype); err != nil {
+					t.Fatalf("error unmarshalling vtype: %v", err)
+				}
+				VTypeTest(stem, vtype, metadata)
+			}
+		}
+	}
+}
+
+// TestMapKeysRejects verifies required, forbidden, allowed, keys_from and malformed entries.
+func TestMapKeysRejects(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "heroes", "Stats\n\"atk=1;def=2\"\n\"atk=3;spd=4\"\n")
+	writeTestCsv(t, dir, "stat_names", "Key\natk\ndef\n")
+	metadata := testMetadata(dir)
+
+	tests := []struct {
+		name    string
+		rule    MapKeys
+		row     int
+		message string
+	}{
+		{"required", MapKeys{Field: "Stats", Required: []string{"def"}}, 3, "required key [def] is missing"},
+		{"forbidden", MapKeys{Field: "Stats", Forbidden: []string{"spd"}}, 3, "key [spd] is forbidden"},
+		{"allowed", MapKeys{Field: "Stats", Allowed: []string{"atk", "def"}}, 3, "not in allowed keys"},
+		{"keys_from", MapKeys{Field: "Stats", KeysFrom: &KeysSource{DstFileStem: "stat_names", Field: "Key"}}, 3, "not found in dst_file [stat_names]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := expectValidationError(t, func() { MapKeysTest("heroes", []MapKeys{tt.rule}, metadata) })
+			if err.Row == nil || *err.Row != tt.row {
+				t.Fatalf("unexpected row: %#v", err.Row)
+			}
+			if !strings.Contains(err.Message, tt.message) {
+				t.Fatalf("unexpected message: %q", err.Message)
+			}
+		})
+	}
+
+	writeTestCsv(t, dir, "heroes", "Stats\n\"atk=1;def\"\n")
+	err := expectValidationError(t, func() { MapKeysTest("heroes", []MapKeys{{Field: "Stats"}}, metadata) })
+	if !strings.Contains(err.Message, "entry [def] is not a key-value pair") {
+		t.Fatalf("unexpected message: %q", err.Message)
+	}
+}
@@ -0,0 +1,165 @@
+package csvons
+
+import (
+	"log"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// outlierDefaultThresholds holds the default Threshold for each method.
+var outlierDefaultThresholds = map[string]float64{
+	"zscore": 3,
+	"iqr":    1.5,
+	"ratio":  10,
+}
+
+// OutlierTest flags numeric values that stand out from the rest of a column.
+//
+// Unlike the other rules it does not abort on findings: every outlier is
+// returned as a warning-severity ValidationError so that designers are
+// notified without failing the run. Non-numeric or non-finite values and
+// invalid rules still abort via failValidation/failRuntime.
+//
+// The zscore method cannot flag anything in small columns: with n values no
+// z-score exceeds sqrt(n-1) (see maxZScore), which is 3 for n = 10.
+func OutlierTest(stem string, ruler []Outlier, metadata *Metadata) []ValidationError {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "outlier"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return nil
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "outlier"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	var warnings []ValidationError
+	for _, outlier := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "outlier", Field: outlier.Field}
+		threshold, ok := outlierDefaultThresholds[outlier.Method]
+		if !ok {
+			failRuntime(ctx, "src_field [%s] outlier method [%s] is not zscore, iqr or ratio", outlier.Field, outlier.Method)
+			return nil
+		}
+		if outlier.Threshold > 0 {
+			threshold = outlier.Threshold
+		}
+		if outlier.Method == "ratio" && threshold <= 1 {
+			failRuntime(ctx, "src_field [%s] ratio threshold [%s] must be greater than 1", outlier.Field, formatFloat(threshold))
+			return nil
+		}
+
+		// Collect the numeric values in row order.
+		fieldExpr := GenerateFieldExpr(metadata, outlier.Field)
+		var occurrences []FieldOccurrence
+		var values []float64
+		for occurrence := range src.occurrences(fieldExpr, outlier.Field, ctx) {
+			v, err := strconv.ParseFloat(occurrence.Value, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				ctx.Row = rowPointer(occurrence.Row)
+				ctx.Value = occurrence.Value
+				failValidation(ctx, "src_field [%s] value [%s] is not a number", outlier.Field, occurrence.Value)
+				return nil
+			}
+			occurrences = append(occurrences, occurrence)
+			values = append(values, v)
+		}
+
+		if outlier.Method == "zscore" && maxZScore(len(values)) <= threshold {
+			log.Printf("src_field [%s] has [%d] values; their z-scores cannot exceed [%s], so threshold [%s] flags nothing", outlier.Field, len(values), formatFloat(maxZScore(len(values))), formatFloat(threshold))
+		}
+		checkCancelled(ctx, metadata)
+		for i, reason := range outlierReasons(outlier.Method, threshold, values) {
+			if i%cancelCheckInterval == 0 {
+				checkCancelled(ctx, metadata)
+			}
+			if reason == "" {
+				continue
+			}
+			ctx.Row = rowPointer(occurrences[i].Row)
+			ctx.Value = occurrences[i].Value
+			warnings = append(warnings, warnValidation(ctx, "src_field [%s] value [%s] is an outlier: %s", outlier.Field, occurrences[i].Value, reason))
+		}
+
+		log.Printf("src_field [%s] outlier check done", outlier.Field)
+	}
+	return warnings
+}
+
+// maxZScore returns the largest |z| any of n values can reach. z-scores use
+// the population standard deviation, under which one value far from n-1
+// equal ones scores sqrt(n-1); so the default threshold of 3 flags nothing
+// in columns of 10 values or fewer. (A sample standard deviation would lower
+// the bound to (n-1)/sqrt(n).)
+func maxZScore(n int) float64 {
+	if n < 1 {
+		return 0
+	}
+	return math.Sqrt(float64(n - 1))
+}
+
+// outlierReasons returns, for each value, why it is an outlier under the
+// method, or "" if it is not.
+func outlierReasons(method string, threshold float64, values []float64) []string {
+	reasons := make([]string, len(values))
+	if len(values) == 0 {
+		return reasons
+	}
+
+	switch method {
+	case "zscore":
+		mean := sumFloats(values) / float64(len(values))
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+		stddev := math.Sqrt(variance / float64(len(values)))
+		if stddev == 0 {
+			return reasons
+		}
+		for i, v := range values {
+			if z := math.Abs(v-mean) / stddev; z > threshold {
+				reasons[i] = "z-score [" + formatFloat(z) + "] exceeds [" + formatFloat(threshold) + "]"
+			}
+		}
+
+	case "iqr":
+		sorted := slices.Clone(values)
+		slices.Sort(sorted)
+		q1, q3 := quantile(sorted, 0.25), quantile(sorted, 0.75)
+		low, high := q1-threshold*(q3-q1), q3+threshold*(q3-q1)
+		for i, v := range values {
+			if v < low || v > high {
+				reasons[i] = "outside IQR fence [" + formatFloat(low) + ", " + formatFloat(high) + "]"
+			}
+		}
+
+	case "ratio":
+		for i := 1; i < len(values); i++ {
+			prev, v := math.Abs(values[i-1]), math.Abs(values[i])
+			if prev == 0 || v == 0 {
+				continue
+			}
+			if ratio := v / prev; ratio > threshold || ratio < 1/threshold {
+				reasons[i] = "ratio [" + formatFloat(ratio) + "] to previous value [" + formatFloat(values[i-1]) + "] exceeds [" + formatFloat(threshold) + "]"
+			}
+		}
+	}
+	return reasons
+}
+
+// quantile returns the q-quantile of sorted values using linear interpolation.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
+
+// formatFloat formats a float for messages with at most four decimals.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+}
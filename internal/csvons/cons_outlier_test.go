@@ -0,0 +1,115 @@
+package csvons
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestOutlierMethods verifies that each method flags the spike as a warning.
+func TestOutlierMethods(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "monsters", "Damage\n500\n510\n490\n50000\n505\n495\n500\n")
+	metadata := testMetadata(dir)
+
+	tests := []struct {
+		method string
+		rows   []int
+	}{
+		{"zscore", []int{5}},
+		{"iqr", []int{5}},
+		{"ratio", []int{5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			threshold := 0.0
+			if tt.method == "zscore" {
+				threshold = 2
+			}
+			warnings := OutlierTest("monsters", []Outlier{{Field: "Damage", Method: tt.method, Threshold: threshold}}, metadata)
+			if len(warnings) != len(tt.rows) {
+				t.Fatalf("unexpected warnings: %+v", warnings)
+			}
+			for i, warning := range warnings {
+				if warning.Severity != "warning" || warning.Row == nil || *warning.Row != tt.rows[i] {
+					t.Fatalf("unexpected warning: %+v", warning)
+				}
+				if !strings.Contains(warning.Message, "is an outlier") {
+					t.Fatalf("unexpected message: %q", warning.Message)
+				}
+			}
+		})
+	}
+}
+
+// TestOutlierNoFindings verifies that a smooth column yields no warnings.
+func TestOutlierNoFindings(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "levels", "Exp\n100\n120\n150\n190\n240\n")
+
+	for _, method := range []string{"zscore", "iqr", "ratio"} {
+		if warnings := OutlierTest("levels", []Outlier{{Field: "Exp", Method: method}}, testMetadata(dir)); len(warnings) != 0 {
+			t.Fatalf("%s: unexpected warnings: %+v", method, warnings)
+		}
+	}
+}
+
+// TestOutlierRejects verifies that non-finite values and ratio thresholds
+// that cannot flag anything abort the rule.
+func TestOutlierRejects(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		rule    Outlier
+		code    int
+		message string
+	}{
+		{"NaN", "Damage\n500\nNaN\n", Outlier{Field: "Damage", Method: "zscore"}, 1, "value [NaN] is not a number"},
+		{"Inf", "Damage\n500\n-Inf\n", Outlier{Field: "Damage", Method: "iqr"}, 1, "value [-Inf] is not a number"},
+		{"ratio threshold", "Damage\n500\n", Outlier{Field: "Damage", Method: "ratio", Threshold: 0.5}, 2, "ratio threshold [0.5] must be greater than 1"},
+		{"ratio threshold of one", "Damage\n500\n", Outlier{Field: "Damage", Method: "ratio", Threshold: 1}, 2, "must be greater than 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestCsv(t, dir, "monsters", tt.content)
+			err := expectValidationError(t, func() { OutlierTest("monsters", []Outlier{tt.rule}, metadata) })
+			if err.Code != tt.code || !strings.Contains(err.Message, tt.message) {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+		})
+	}
+}
+
+// TestOutlierZScoreSmallColumns verifies the documented z-score limit: one
+// extreme value among n scores sqrt(n-1), so the default threshold of 3
+// needs more than 10 values.
+func TestOutlierZScoreSmallColumns(t *testing.T) {
+	for n := 2; n <= 12; n++ {
+		values := make([]float64, n)
+		values[0] = 1e6
+		mean := values[0] / float64(n)
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+		if z := (values[0] - mean) / math.Sqrt(variance/float64(n)); math.Abs(z-maxZScore(n)) > 1e-9 {
+			t.Fatalf("n=%d: z-score %v, maxZScore %v", n, z, maxZScore(n))
+		}
+		if n == 10 {
+			continue // z is exactly 3, the threshold itself.
+		}
+		if flagged := outlierReasons("zscore", 3, values)[0] != ""; flagged != (n > 10) {
+			t.Fatalf("n=%d: flagged=%v with max z-score %v", n, flagged, maxZScore(n))
+		}
+	}
+
+	// A lower threshold still flags the value in a small column.
+	if reasons := outlierReasons("zscore", 1.9, []float64{1e6, 0, 0, 0, 0}); reasons[0] == "" {
+		t.Fatalf("expected the extreme value to be flagged: %q", reasons)
+	}
+}
@@ -0,0 +1,121 @@
+package csvons
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// maxSequenceGaps is the number of gaps a sequence error lists; further
+// gaps are only counted.
+const maxSequenceGaps = 100
+
+// SequenceTest validates that the integer values of each field expression
+// form a contiguous sequence in row order: every value must equal the
+// previous value plus Step (default 1), and the first value must equal
+// Start when it is set.
+//
+// The whole column is scanned before failing so the error can report the
+// first break together with the gaps (missing values) in the sequence; only
+// the first maxSequenceGaps gaps are listed, so memory stays bounded.
+func SequenceTest(stem string, ruler []Sequence, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "sequence"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "sequence"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, sequence := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "sequence", Field: sequence.Field}
+		step := sequence.Step
+		if step == 0 {
+			step = 1
+		}
+
+		fieldExpr := GenerateFieldExpr(metadata, sequence.Field)
+		fieldVals := src.occurrences(fieldExpr, sequence.Field, ctx)
+
+		var firstBreak *ValidationContext
+		var breakMessage string
+		var gaps []string
+		omittedGaps := 0
+		var prev int64
+		first := true
+		for occurrence := range fieldVals {
+			v, err := strconv.ParseInt(occurrence.Value, 10, 64)
+			if err != nil {
+				ctx.Row = rowPointer(occurrence.Row)
+				ctx.Value = occurrence.Value
+				failValidation(ctx, "src_field [%s] value [%s] is not an int", sequence.Field, occurrence.Value)
+				return
+			}
+
+			var expected int64
+			switch {
+			case first && sequence.Start == nil:
+				expected = v
+			case first:
+				expected = *sequence.Start
+			default:
+				expected = prev + step
+			}
+			first = false
+
+			if v != expected {
+				if firstBreak == nil {
+					breakCtx := ctx
+					breakCtx.Row = rowPointer(occurrence.Row)
+					breakCtx.Value = occurrence.Value
+					firstBreak = &breakCtx
+					breakMessage = fmt.Sprintf("value [%d] at row [%d], expected [%d]", v, occurrence.Row, expected)
+				}
+				if gap := sequenceGap(expected, v, step); gap != "" {
+					if len(gaps) < maxSequenceGaps {
+						gaps = append(gaps, gap)
+					} else {
+						omittedGaps++
+					}
+				}
+			}
+			prev = v
+		}
+
+		if firstBreak != nil {
+			if omittedGaps > 0 {
+				log.Printf("warning: src_field [%s] has [%d] gaps; only the first [%d] are listed", sequence.Field, len(gaps)+omittedGaps, maxSequenceGaps)
+				gaps = append(gaps, fmt.Sprintf("... %d more", omittedGaps))
+			}
+			failValidation(
+				*firstBreak,
+				"src_field [%s] sequence breaks at %s; gaps: [%s]",
+				sequence.Field,
+				breakMessage,
+				strings.Join(gaps, ", "),
+			)
+			return
+		}
+
+		log.Printf("src_field [%s] values form a contiguous sequence", sequence.Field)
+	}
+}
+
+// sequenceGap describes the values skipped between expected and the actual
+// value v, e.g. "4" or "4..7". It returns "" when v does not skip ahead
+// (a repeated or backwards value is a break but not a gap).
+func sequenceGap(expected, v, step int64) string {
+	last := v - step
+	if (step > 0 && last < expected) || (step < 0 && last > expected) {
+		return ""
+	}
+	if last == expected {
+		return strconv.FormatInt(expected, 10)
+	}
+	return fmt.Sprintf("%d..%d", expected, last)
+}
@@ -0,0 +1,86 @@
+package csvons
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSequenceLevels validates sequence constraints using the levels test data.
+func TestSequenceLevels(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_levels.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "sequence" {
+				var sequence []Sequence
+				if err := json.Unmarshal(v, &sequence); err != nil {
+					t.Fatalf("error unmarshalling sequence: %v", err)
+				}
+				SequenceTest(stem, sequence, metadata)
+			}
+		}
+	}
+}
+
+// TestSequenceReportsAllGaps verifies that the first break and every gap are reported.
+func TestSequenceReportsAllGaps(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "ids", "ID\n1\n2\n4\n5\n9\n")
+	metadata := testMetadata(dir)
+
+	err := expectValidationError(t, func() { SequenceTest("ids", []Sequence{{Field: "ID"}}, metadata) })
+	if err.Row == nil || *err.Row != 4 || err.Value != "4" {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !strings.Contains(err.Message, "gaps: [3, 6..8]") {
+		t.Fatalf("unexpected message: %q", err.Message)
+	}
+}
+
+// TestSequenceCapsGaps verifies that only the first maxSequenceGaps gaps
+// are listed and the rest are counted.
+func TestSequenceCapsGaps(t *testing.T) {
+	dir := t.TempDir()
+	var b strings.Builder
+	b.WriteString("ID\n")
+	for i := range maxSequenceGaps + 5 {
+		fmt.Fprintf(&b, "%d\n", i*2)
+	}
+	writeTestCsv(t, dir, "ids", b.String())
+
+	err := expectValidationError(t, func() { SequenceTest("ids", []Sequence{{Field: "ID"}}, testMetadata(dir)) })
+	if !strings.Contains(err.Message, "gaps: [1, 3, 5") || !strings.HasSuffix(err.Message, ", 199, ... 4 more]") {
+		t.Fatalf("unexpected message: %q", err.Message)
+	}
+}
+
+// TestSequenceStartAndStep verifies the start value and a custom step.
+func TestSequenceStartAndStep(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "ids", "ID\n10\n20\n30\n")
+	metadata := testMetadata(dir)
+
+	start := int64(10)
+	SequenceTest("ids", []Sequence{{Field: "ID", Start: &start, Step: 10}}, metadata)
+
+	start = 0
+	err := expectValidationError(t, func() { SequenceTest("ids", []Sequence{{Field: "ID", Start: &start, Step: 10}}, metadata) })
+	if err.Row == nil || *err.Row != 2 || !strings.Contains(err.Message, "expected [0]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package csvons
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// SortedTest validates that the values of each field expression appear in
+// the requested order, comparing neighbouring values in row order.
+//
+// Values are compared according to the rule's Type ("string", "int" or
+// "float64"); numeric values that cannot be parsed are reported as errors.
+// The first out-of-order value is reported together with its predecessor.
+func SortedTest(stem string, ruler []Sorted, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if len(ruler) == 0 || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "sorted"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredRecordSource(ValidationContext{File: fileName, Rule: "sorted"}, stem, metadata)
+	log.Printf("src_fields: %q", src.table.Fields)
+
+	for _, sorted := range ruler {
+		ctx := ValidationContext{File: fileName, Rule: "sorted", Field: sorted.Field}
+		if sorted.Order != "" && sorted.Order != "asc" && sorted.Order != "desc" {
+			failRuntime(ctx, "src_field [%s] order [%s] is not asc or desc", sorted.Field, sorted.Order)
+			return
+		}
+
+		fieldExpr := GenerateFieldExpr(metadata, sorted.Field)
+		fieldVals := src.occurrences(fieldExpr, sorted.Field, ctx)
+
+		var prev *FieldOccurrence
+		for occurrence := range fieldVals {
+			ctx.Row = rowPointer(occurrence.Row)
+			ctx.Value = occurrence.Value
+			if prev != nil {
+				order, err := compareTyped(prev.Value, occurrence.Value, sorted.Type)
+				if err != nil {
+					failValidation(ctx, "src_field [%s] value [%s] %v", sorted.Field, occurrence.Value, err)
+					return
+				}
+				if sorted.Order == "desc" {
+					order = -order
+				}
+				if order > 0 || (order == 0 && sorted.Strict) {
+					failValidation(
+						ctx,
+						"src_field [%s] value [%s] at row [%d] is out of %s order after [%s] at row [%d]",
+						sorted.Field,
+						occurrence.Value,
+						occurrence.Row,
+						sortedOrderName(sorted),
+						prev.Value,
+						prev.Row,
+					)
+					return
+				}
+			}
+			prev = &occurrence
+		}
+
+		log.Printf("src_field [%s] values are sorted", sorted.Field)
+	}
+}
+
+// compareTyped compares two cell values as the given type and returns
+// -1, 0 or +1. An empty type compares values as strings.
+func compareTyped(a, b, valueType string) (int, error) {
+	switch valueType {
+	case "", "string":
+		return strings.Compare(a, b), nil
+	case "int":
+		x, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("[%s] is not an int", a)
+		}
+		y, err := strconv.ParseInt(b, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("[%s] is not an int", b)
+		}
+		return cmp.Compare(x, y), nil
+	case "float64":
+		x, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return 0, fmt.Errorf("[%s] is not a float64", a)
+		}
+		y, err := strconv.ParseFloat(b, 64)
+		if err != nil {
+			return 0, fmt.Errorf("[%s] is not a float64", b)
+		}
+		return cmp.Compare(x, y), nil
+	default:
+		return 0, fmt.Errorf("type [%s] is not a valid type", valueType)
+	}
+}
+
+// sortedOrderName describes the rule's order for error messages,
+// e.g. "strictly asc".
+func sortedOrderName(sorted Sorted) string {
+	order := sorted.Order
+	if order == "" {
+		order = "asc"
+	}
+	if sorted.Strict {
+		return "strictly " + order
+	}
+	return order
+}
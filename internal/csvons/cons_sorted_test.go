@@ -0,0 +1,63 @@
+package csvons
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSortedLevels validates sorted constraints using the levels test data.
+func TestSortedLevels(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_levels.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "sorted" {
+				var sorted []Sorted
+				if err := json.Unmarshal(v, &sorted); err != nil {
+					t.Fatalf("error unmarshalling sorted: %v", err)
+				}
+				SortedTest(stem, sorted, metadata)
+			}
+		}
+	}
+}
+
+// TestSortedRejects verifies typed, strict and descending comparisons.
+func TestSortedRejects(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "levels", "Level,Exp\n2,10\n9,20\n10,20\n")
+	metadata := testMetadata(dir)
+
+	// "10" < "9" as strings, but not as ints.
+	SortedTest("levels", []Sorted{{Field: "Level", Type: "int"}}, metadata)
+	SortedTest("levels", []Sorted{{Field: "Exp", Type: "int"}}, metadata)
+
+	err := expectValidationError(t, func() { SortedTest("levels", []Sorted{{Field: "Level"}}, metadata) })
+	if err.Row == nil || *err.Row != 4 || err.Value != "10" {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	err = expectValidationError(t, func() { SortedTest("levels", []Sorted{{Field: "Exp", Type: "int", Strict: true}}, metadata) })
+	if err.Row == nil || *err.Row != 4 || !strings.Contains(err.Message, "strictly asc") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	err = expectValidationError(t, func() { SortedTest("levels", []Sorted{{Field: "Level", Order: "desc", Type: "int"}}, metadata) })
+	if err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "after [2] at row [2]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
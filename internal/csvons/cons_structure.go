@@ -0,0 +1,138 @@
+package csvons
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// StructureTest validates the shape of every record in a CSV file.
+//
+// The file is read leniently (ragged rows are accepted by the reader) so that
+// structural problems surface as validation errors instead of read failures.
+// It then:
+//  1. Rejects header names with leading or trailing whitespace
+//  2. Checks each preamble row (between name_index and data_index) against its expected shape
+//  3. Rejects rows whose cell count differs from the header
+//  4. Rejects data rows where every cell is blank
+//
+// Each check can be relaxed through the corresponding Structure option.
+// Note that encoding/csv skips completely empty lines, so only rows made of
+// separators and whitespace (e.g. ",,") are reported as blank.
+func StructureTest(stem string, ruler *Structure, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if ruler == nil || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "structure"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredSourceTable(ValidationContext{File: fileName, Rule: "structure"}, stem, metadata, -1)
+	log.Printf("src_fields: %q", src.Fields)
+
+	// Header names must be usable as field expressions as written.
+	if !ruler.AllowHeaderWhitespace {
+		for _, fieldName := range src.Fields {
+			if strings.TrimSpace(fieldName) != fieldName {
+				failValidation(
+					ValidationContext{
+						File:  fileName,
+						Rule:  "structure",
+						Field: fieldName,
+						Row:   rowPointer(metadata.NameIndex + 1),
+						Value: fieldName,
+					},
+					"header [%q] has leading or trailing whitespace",
+					fieldName,
+				)
+				return
+			}
+		}
+	}
+
+	// Rows between the header and the data must match their declared shape.
+	for _, preamble := range ruler.Preamble {
+		checkPreambleRow(fileName, preamble, src.Records, metadata)
+	}
+
+	for i := metadata.NameIndex + 1; i < len(src.Records); i++ {
+		record := src.Records[i]
+
+		if !ruler.AllowRagged && len(record) != len(src.Fields) {
+			failValidation(
+				ValidationContext{
+					File:  fileName,
+					Rule:  "structure",
+					Row:   rowPointer(i + 1),
+					Value: strings.Join(record, ","),
+				},
+				"row [%d] has [%d] cells, header has [%d]",
+				i+1,
+				len(record),
+				len(src.Fields),
+			)
+			return
+		}
+
+		if !ruler.AllowBlankRows && i >= metadata.DataIndex && isBlankRecord(record) {
+			failValidation(
+				ValidationContext{
+					File: fileName,
+					Rule: "structure",
+					Row:  rowPointer(i + 1),
+				},
+				"row [%d] is blank",
+				i+1,
+			)
+			return
+		}
+	}
+
+	log.Printf("src file %s structure is valid", stem)
+}
+
+// checkPreambleRow validates a single row between name_index and data_index
+// against its expected values and cell pattern.
+func checkPreambleRow(fileName string, preamble PreambleRow, records [][]string, metadata *Metadata) {
+	ctx := ValidationContext{File: fileName, Rule: "structure", Row: rowPointer(preamble.Row + 1)}
+	if preamble.Row <= metadata.NameIndex || preamble.Row >= metadata.DataIndex {
+		failRuntime(ctx, "preamble row [%d] is not between name_index [%d] and data_index [%d]", preamble.Row, metadata.NameIndex, metadata.DataIndex)
+		return
+	}
+
+	record := records[preamble.Row]
+	if preamble.Values != nil && !slices.Equal(record, preamble.Values) {
+		ctx.Value = strings.Join(record, ",")
+		failValidation(ctx, "preamble row [%d] values %q do not match expected %q", preamble.Row+1, record, preamble.Values)
+		return
+	}
+
+	if preamble.Pattern == "" {
+		return
+	}
+	pattern, err := regexp.Compile(preamble.Pattern)
+	if err != nil {
+		failRuntime(ctx, "preamble row [%d] pattern [%s] is invalid: %v", preamble.Row+1, preamble.Pattern, err)
+		return
+	}
+	for _, cell := range record {
+		if !pattern.MatchString(cell) {
+			ctx.Value = cell
+			failValidation(ctx, "preamble row [%d] cell [%s] does not match pattern [%s]", preamble.Row+1, cell, preamble.Pattern)
+			return
+		}
+	}
+}
+
+// isBlankRecord reports whether every cell in the record is empty or whitespace.
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
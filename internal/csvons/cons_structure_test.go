@@ -0,0 +1,95 @@
+package csvons
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStructureProducts validates structure constraints using the products test data.
+func TestStructureProducts(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_products.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "structure" {
+				var structure Structure
+				if err := json.Unmarshal(v, &structure); err != nil {
+					t.Fatalf("error unmarshalling structure: %v", err)
+				}
+				StructureTest(stem, &structure, metadata)
+			}
+		}
+	}
+}
+
+// TestStructureRejects verifies that each structural problem is reported
+// with the offending row.
+func TestStructureRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		ruler   Structure
+		row     int
+		message string
+	}{
+		{"short row", "ID,Name\n1,a\n2\n", Structure{}, 3, "has [1] cells"},
+		{"long row", "ID,Name\n1,a,extra\n", Structure{}, 2, "has [3] cells"},
+		{"blank row", "ID,Name\n1,a\n,\n", Structure{}, 3, "is blank"},
+		{"header whitespace", "ID,Name \n1,a\n", Structure{}, 1, "whitespace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestCsv(t, dir, "table", tt.content)
+
+			err := expectValidationError(t, func() { StructureTest("table", &tt.ruler, testMetadata(dir)) })
+			if err.Row == nil || *err.Row != tt.row {
+				t.Fatalf("unexpected row: %#v", err.Row)
+			}
+			if !strings.Contains(err.Message, tt.message) {
+				t.Fatalf("unexpected message: %q", err.Message)
+			}
+		})
+	}
+}
+
+// TestStructureAllowOptions verifies that relaxed options accept the same problems.
+func TestStructureAllowOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "table", "ID,Name \n1,a\n2\n,\n")
+
+	StructureTest("table", &Structure{AllowRagged: true, AllowBlankRows: true, AllowHeaderWhitespace: true}, testMetadata(dir))
+}
+
+// TestStructurePreamble verifies checks on rows between name_index and data_index.
+func TestStructurePreamble(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "table", "ID,Name\nint,string\n1,a\n")
+	metadata := testMetadata(dir)
+	metadata.DataIndex = 2
+
+	StructureTest("table", &Structure{Preamble: []PreambleRow{{Row: 1, Pattern: "^(int|string)$"}}}, metadata)
+	StructureTest("table", &Structure{Preamble: []PreambleRow{{Row: 1, Values: []string{"int", "string"}}}}, metadata)
+
+	err := expectValidationError(t, func() {
+		StructureTest("table", &Structure{Preamble: []PreambleRow{{Row: 1, Pattern: "^int$"}}}, metadata)
+	})
+	if err.Row == nil || *err.Row != 2 || err.Value != "string" {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
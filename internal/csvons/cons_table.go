@@ -0,0 +1,158 @@
+package csvons
+
+import (
+	"log"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// TableTest validates table-level constraints of a CSV file.
+//
+// It checks:
+//  1. The number of data rows against the optional CountRange bounds
+//  2. Each aggregate (sum, min, max, avg, count_distinct) computed over a
+//     field expression, either across the whole table or per group
+//
+// When an aggregate has GroupBy set, every row is assigned to the group named
+// by the value of the GroupBy expression in that row, and the aggregate is
+// checked once per group, in sorted group order. A group (or the whole
+// table) whose field yields no values fails when the aggregate sets a bound.
+func TableTest(stem string, ruler *TableRule, metadata *Metadata) {
+	fileName := csvFileName(stem, metadata)
+
+	// Validate input parameters.
+	if ruler == nil || metadata == nil {
+		failRuntime(ValidationContext{File: fileName, Rule: "table"}, "ruler [%v] or metadata [%v] is nil", ruler, metadata)
+		return
+	}
+	log.Printf("checking src file %s ...", stem)
+
+	src := requiredSourceTable(ValidationContext{File: fileName, Rule: "table"}, stem, metadata, 0)
+	log.Printf("src_fields: %q", src.Fields)
+
+	// Check the data row count.
+	if ruler.Rows != nil {
+		rowCount := len(src.Records) - metadata.DataIndex
+		ctx := ValidationContext{File: fileName, Rule: "table", Value: strconv.Itoa(rowCount)}
+		if ruler.Rows.Min != nil && rowCount < *ruler.Rows.Min {
+			failValidation(ctx, "data row count [%d] is less than min [%d]", rowCount, *ruler.Rows.Min)
+			return
+		}
+		if ruler.Rows.Max != nil && rowCount > *ruler.Rows.Max {
+			failValidation(ctx, "data row count [%d] is greater than max [%d]", rowCount, *ruler.Rows.Max)
+			return
+		}
+		log.Printf("data row count [%d] is within bounds", rowCount)
+	}
+
+	for _, aggregate := range ruler.Aggregates {
+		checkAggregate(fileName, aggregate, src, metadata)
+	}
+}
+
+// aggregateFuncs lists the aggregate function names accepted by Aggregate.Func.
+var aggregateFuncs = []string{"sum", "min", "max", "avg", "count_distinct"}
+
+// checkAggregate computes one aggregate (per group, if grouped) and verifies
+// it against the expected value and range.
+func checkAggregate(fileName string, aggregate Aggregate, table *Table, metadata *Metadata) {
+	ctx := ValidationContext{File: fileName, Rule: "table", Field: aggregate.Field}
+	if !slices.Contains(aggregateFuncs, aggregate.Func) {
+		failRuntime(ctx, "src_field [%s] aggregate func [%s] is not supported", aggregate.Field, aggregate.Func)
+		return
+	}
+
+	// Assign each row to its group; ungrouped aggregates use a single "" group.
+	rowGroups := make(map[int]string)
+	if aggregate.GroupBy != "" {
+		rowGroups = requiredRowValues(metadata, aggregate.GroupBy, table, ctx)
+	}
+
+	// Every group is checked, including groups (or an ungrouped table)
+	// where the field yields no values.
+	groupValues := map[string][]string{}
+	if aggregate.GroupBy == "" {
+		groupValues[""] = nil
+	}
+	for _, group := range rowGroups {
+		groupValues[group] = nil
+	}
+	fieldExpr := GenerateFieldExpr(metadata, aggregate.Field)
+	for occurrence := range requiredFieldOccurrences(metadata, fieldExpr, aggregate.Field, table, ctx) {
+		group := rowGroups[occurrence.Row]
+		groupValues[group] = append(groupValues[group], occurrence.Value)
+	}
+
+	groups := make([]string, 0, len(groupValues))
+	for group := range groupValues {
+		groups = append(groups, group)
+	}
+	slices.Sort(groups)
+
+	for _, group := range groups {
+		if len(groupValues[group]) == 0 {
+			// Without values there is no aggregate to hold to the bounds.
+			if aggregate.Equals != nil || aggregate.Range != nil {
+				ctx.Value = ""
+				failValidation(ctx, "src_field [%s] of group [%s] has no values to compute %s", aggregate.Field, group, aggregate.Func)
+				return
+			}
+			continue
+		}
+		result := aggregateValues(ctx, aggregate, groupValues[group])
+		log.Printf("src_field [%s] %s of group [%s] is [%v]", aggregate.Field, aggregate.Func, group, result)
+
+		ctx.Value = strconv.FormatFloat(result, 'f', -1, 64)
+		if aggregate.Equals != nil && math.Abs(result-*aggregate.Equals) > aggregate.Tolerance {
+			failValidation(ctx, "src_field [%s] %s [%v] of group [%s] is not equal to [%v]", aggregate.Field, aggregate.Func, result, group, *aggregate.Equals)
+			return
+		}
+		if aggregate.Range != nil && (result < aggregate.Range.Min-aggregate.Tolerance || result > aggregate.Range.Max+aggregate.Tolerance) {
+			failValidation(ctx, "src_field [%s] %s [%v] of group [%s] is not in the range [%v, %v]", aggregate.Field, aggregate.Func, result, group, aggregate.Range.Min, aggregate.Range.Max)
+			return
+		}
+	}
+}
+
+// aggregateValues applies the aggregate function to a group's values.
+// Numeric functions abort via failValidation on values that are not numbers.
+func aggregateValues(ctx ValidationContext, aggregate Aggregate, values []string) float64 {
+	if aggregate.Func == "count_distinct" {
+		distinct := make(map[string]bool)
+		for _, value := range values {
+			distinct[value] = true
+		}
+		return float64(len(distinct))
+	}
+
+	numbers := make([]float64, 0, len(values))
+	for _, value := range values {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			ctx.Value = value
+			failValidation(ctx, "src_field [%s] value [%s] is not a number", aggregate.Field, value)
+			return 0
+		}
+		numbers = append(numbers, v)
+	}
+
+	switch aggregate.Func {
+	case "sum":
+		return sumFloats(numbers)
+	case "avg":
+		return sumFloats(numbers) / float64(len(numbers))
+	case "min":
+		return slices.Min(numbers)
+	default: // "max"
+		return slices.Max(numbers)
+	}
+}
+
+func sumFloats(numbers []float64) float64 {
+	sum := 0.0
+	for _, v := range numbers {
+		sum += v
+	}
+	return sum
+}
@@ -0,0 +1,133 @@
+package csvons
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTableEmployees validates table constraints using the employees test data.
+// Tests the row count, count_distinct, and a grouped avg aggregate.
+func TestTableEmployees(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_employees.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "table" {
+				var table TableRule
+				if err := json.Unmarshal(v, &table); err != nil {
+					t.Fatalf("error unmarshalling table: %v", err)
+				}
+				TableTest(stem, &table, metadata)
+			}
+		}
+	}
+}
+
+// TestTableRowCount verifies the row count bounds.
+func TestTableRowCount(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "levels", "Level\n1\n2\n3\n")
+	metadata := testMetadata(dir)
+
+	three := 3
+	TableTest("levels", &TableRule{Rows: &CountRange{Min: &three, Max: &three}}, metadata)
+
+	ten := 10
+	err := expectValidationError(t, func() { TableTest("levels", &TableRule{Rows: &CountRange{Min: &ten}}, metadata) })
+	if err.Value != "3" || !strings.Contains(err.Message, "less than min [10]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+// TestTableGroupedSum verifies per-group sums with a float tolerance.
+func TestTableGroupedSum(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "drops", "Group,Rate\na,33.3\na,33.3\na,33.4\nb,50\nb,40\n")
+	metadata := testMetadata(dir)
+
+	hundred := 100.0
+	aggregate := Aggregate{Field: "Rate", Func: "sum", GroupBy: "Group", Equals: &hundred, Tolerance: 1e-9}
+	err := expectValidationError(t, func() {
+		TableTest("drops", &TableRule{Aggregates: []Aggregate{aggregate}}, metadata)
+	})
+	if err.Field != "Rate" || err.Value != "90" || !strings.Contains(err.Message, "group [b]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	writeTestCsv(t, dir, "drops", "Group,Rate\na,33.3\na,33.3\na,33.4\nb,50\nb,50\n")
+	TableTest("drops", &TableRule{Aggregates: []Aggregate{aggregate}}, metadata)
+}
+
+// TestTableGroupByRejectsMultipleValues verifies that a group_by expression
+// yielding several values in one row fails instead of keeping one of them.
+func TestTableGroupByRejectsMultipleValues(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "drops", "Group,Rate\na,50\n\"a;b\",50\n")
+
+	hundred := 100.0
+	aggregate := Aggregate{Field: "Rate", Func: "sum", GroupBy: "Group[]", Equals: &hundred}
+	err := expectValidationError(t, func() {
+		TableTest("drops", &TableRule{Aggregates: []Aggregate{aggregate}}, testMetadata(dir))
+	})
+	if err.Code != 2 || err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "yields more than one value in a row") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+// TestTableAggregateRejectsNonNumeric verifies numeric aggregates reject text values.
+func TestTableAggregateRejectsNonNumeric(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "drops", "Rate\n10\nten\n")
+
+	err := expectValidationError(t, func() {
+		TableTest("drops", &TableRule{Aggregates: []Aggregate{{Field: "Rate", Func: "max"}}}, testMetadata(dir))
+	})
+	if err.Value != "ten" || err.Code != 1 {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+// TestTableAggregateWithoutValues verifies that a bounded aggregate fails
+// when the table or a group yields no values instead of passing unchecked.
+func TestTableAggregateWithoutValues(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "drops", "Group,Drops\na,sword\nb,shield\n")
+	metadata := testMetadata(dir)
+
+	hundred := 100.0
+	aggregate := Aggregate{Field: "Drops{1}", Func: "sum", Equals: &hundred}
+	err := expectValidationError(t, func() {
+		TableTest("drops", &TableRule{Aggregates: []Aggregate{aggregate}}, metadata)
+	})
+	if err.Field != "Drops{1}" || !strings.Contains(err.Message, "has no values") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	// Only group a has a drop weight.
+	writeTestCsv(t, dir, "drops", "Group,Drops\na,sword:100\nb,shield\n")
+	aggregate = Aggregate{Field: "Drops{1}", Func: "sum", GroupBy: "Group", Equals: &hundred}
+	err = expectValidationError(t, func() {
+		TableTest("drops", &TableRule{Aggregates: []Aggregate{aggregate}}, metadata)
+	})
+	if !strings.Contains(err.Message, "group [b] has no values") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	// Unbounded aggregates have nothing to check.
+	aggregate.Equals = nil
+	TableTest("drops", &TableRule{Aggregates: []Aggregate{aggregate}}, metadata)
+}
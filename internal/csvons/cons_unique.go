@@ -2,14 +2,18 @@ package csvons
 
 import (
 	"log"
+	"strconv"
 )
 
 // UniqueTest validates that all values in specified columns of a CSV file are unique.
+// The ruler's Scope narrows uniqueness to each row or to each group of rows.
 //
 // For each field name in the ruler's Fields list, it:
 //  1. Creates a field expression from the field name
 //  2. Extracts all values from the corresponding column
-//  3. Counts occurrences and fails if any value appears more than once
+//  3. Counts occurrences per scope and fails if any value appears more than once
+//
+// In streaming mode only the seen values (and group keys) are kept in memory.
 //
 // Calls log.Fatalf if any duplicate is found or if parameters are invalid.
 func UniqueTest(stem string, ruler *Unique, metadata *Metadata) {
@@ -22,48 +26,45 @@ func UniqueTest(stem string, ruler *Unique, metadata *Metadata) {
 	}
 	log.Printf("checking src file %s ...", stem)
 
-	// Validate metadata indices.
-	nameIndex := metadata.NameIndex
-	if nameIndex < 0 {
-		failRuntime(ValidationContext{File: fileName, Rule: "unique"}, "name_index [%d] is less than 0", nameIndex)
-		return
-	}
-	log.Printf("name_index: %d", nameIndex)
+	// Validate metadata indices and open the source CSV file.
+	source := requiredRecordSource(ValidationContext{File: fileName, Rule: "unique"}, stem, metadata)
+	log.Printf("src_fields: %q", source.table.Fields)
 
-	dataIndex := metadata.DataIndex
-	if dataIndex <= nameIndex {
-		failRuntime(ValidationContext{File: fileName, Rule: "unique"}, "data_index [%d] is less than or equal to name_index [%d]", dataIndex, nameIndex)
-		return
-	}
-	log.Printf("data_index: %d", dataIndex)
-
-	// Read the source CSV file and validate it has enough rows.
-	srcRecords := ReadCsvFile(stem, metadata)
-	if srcLen := len(srcRecords); srcLen <= dataIndex {
-		failRuntime(ValidationContext{File: fileName, Rule: "unique"}, "src_records length [%d] <= data_index [%d]", srcLen, dataIndex)
+	// Resolve the scope key of each row; table scope puts every row in one scope.
+	rowScopes := map[int]string{}
+	switch ruler.Scope {
+	case "", "table", "row":
+	case "group":
+		if ruler.GroupBy == "" {
+			failRuntime(ValidationContext{File: fileName, Rule: "unique"}, "group_by is required for scope [group]")
+			return
+		}
+		rowScopes = source.rowValues(ruler.GroupBy, ValidationContext{File: fileName, Rule: "unique"})
+	default:
+		failRuntime(ValidationContext{File: fileName, Rule: "unique"}, "scope [%s] is not table, row or group", ruler.Scope)
 		return
 	}
-	srcFields := srcRecords[nameIndex]
-	log.Printf("src_fields: %q", srcFields)
 
 	// Check uniqueness for each specified field.
 	for _, fieldName := range ruler.Fields {
 		// Create field expression to extract values from the column.
 		fieldExpr := GenerateFieldExpr(metadata, fieldName)
-		fieldVals := requiredFieldOccurrences(
+		fieldVals := source.occurrences(
 			fieldExpr,
 			fieldName,
-			srcFields,
-			srcRecords,
 			ValidationContext{File: fileName, Rule: "unique", Field: fieldName},
 		)
 
-		// Track value occurrences; fail on any duplicate.
-		existingFields := make(map[string]int)
+		// Track value occurrences per scope; fail on any duplicate.
+		existingFields := make(map[uniqueKey]int)
 		for occurrence := range fieldVals {
 			fieldVal := occurrence.Value
-			existingFields[fieldVal] += 1
-			if existingFields[fieldVal] > 1 {
+			key := uniqueKey{scope: rowScopes[occurrence.Row], value: fieldVal}
+			if ruler.Scope == "row" {
+				key.scope = strconv.Itoa(occurrence.Row)
+			}
+			existingFields[key] += 1
+			if existingFields[key] > 1 {
 				failValidation(
 					ValidationContext{
 						File:  fileName,
@@ -72,9 +73,10 @@ func UniqueTest(stem string, ruler *Unique, metadata *Metadata) {
 						Row:   rowPointer(occurrence.Row),
 						Value: fieldVal,
 					},
-					"src_field [%s] value [%s] already exists",
+					"src_field [%s] value [%s] already exists%s",
 					fieldName,
 					fieldVal,
+					uniqueScopeSuffix(ruler, key),
 				)
 			}
 		}
@@ -82,3 +84,21 @@ func UniqueTest(stem string, ruler *Unique, metadata *Metadata) {
 		log.Printf("src_field [%s] values are unique", fieldName)
 	}
 }
+
+// uniqueKey identifies a value within its uniqueness scope.
+type uniqueKey struct {
+	scope string
+	value string
+}
+
+// uniqueScopeSuffix describes the scope of a duplicate for error messages.
+func uniqueScopeSuffix(ruler *Unique, key uniqueKey) string {
+	switch ruler.Scope {
+	case "row":
+		return " in the same row"
+	case "group":
+		return " in group [" + key.scope + "]"
+	default:
+		return ""
+	}
+}
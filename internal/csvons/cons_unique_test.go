@@ -3,6 +3,7 @@ package csvons
 import (
 	"encoding/json"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -122,3 +123,31 @@ func TestUniqueEmployees(t *testing.T) {
 		}
 	}
 }
+
+// TestUniqueScopes verifies row and group scoped uniqueness.
+func TestUniqueScopes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "items", "Group,Tags\na,\"x;y\"\na,\"y;z\"\nb,\"x;z\"\n")
+	metadata := testMetadata(dir)
+
+	// The same tag appears in different rows, but never twice in one row.
+	UniqueTest("items", &Unique{Fields: []string{"Tags[]"}, Scope: "row"}, metadata)
+
+	err := expectValidationError(t, func() { UniqueTest("items", &Unique{Fields: []string{"Tags[]"}}, metadata) })
+	if err.Row == nil || *err.Row != 3 || err.Value != "y" {
+		t.Fatalf("unexpected table scope error: %+v", err)
+	}
+
+	err = expectValidationError(t, func() {
+		UniqueTest("items", &Unique{Fields: []string{"Tags[]"}, Scope: "group", GroupBy: "Group"}, metadata)
+	})
+	if err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "in group [a]") {
+		t.Fatalf("unexpected group scope error: %+v", err)
+	}
+
+	writeTestCsv(t, dir, "items", "Group,Tags\na,\"x;y;x\"\n")
+	err = expectValidationError(t, func() { UniqueTest("items", &Unique{Fields: []string{"Tags[]"}, Scope: "row"}, metadata) })
+	if err.Row == nil || *err.Row != 2 || !strings.Contains(err.Message, "in the same row") {
+		t.Fatalf("unexpected row scope error: %+v", err)
+	}
+}
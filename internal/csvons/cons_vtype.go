@@ -12,12 +12,17 @@ import (
 //   - "int": values must be parseable as 64-bit integers
 //   - "float64": values must be parseable as 64-bit floats
 //   - "bool": values must be parseable as booleans (true/false, 1/0, etc.)
+//   - string formats: "email", "url", "uuid", "color_hex", "semver", "ipv4",
+//     "ipv6", "country_code", "currency_code", "base64" (see formatValidators)
+//   - "json": values must parse as JSON and match the optional JSON Schema
 //
 // For "int" and "float64" types, an optional Range constraint can specify
 // minimum and maximum allowed values (inclusive).
 //
 // A per-field cache (typedSearchedFieldCache) skips re-checking values that
 // have already been validated, improving performance for repeated values.
+// In streaming mode each rule is one pass over the file and no records are
+// kept.
 func VTypeTest(stem string, ruler []VType, metadata *Metadata) {
 	fileName := csvFileName(stem, metadata)
 
@@ -28,45 +33,35 @@ func VTypeTest(stem string, ruler []VType, metadata *Metadata) {
 	}
 	log.Printf("checking src file %s ...", stem)
 
-	// Validate metadata indices.
-	nameIndex := metadata.NameIndex
-	if nameIndex < 0 {
-		failRuntime(ValidationContext{File: fileName, Rule: "vtype"}, "name_index [%d] is less than 0", nameIndex)
-		return
-	}
-	log.Printf("name_index: %d", nameIndex)
-
-	dataIndex := metadata.DataIndex
-	if dataIndex <= nameIndex {
-		failRuntime(ValidationContext{File: fileName, Rule: "vtype"}, "data_index [%d] is less than or equal to name_index [%d]", dataIndex, nameIndex)
-		return
-	}
-	log.Printf("data_index: %d", dataIndex)
-
-	// Read the source CSV file and validate it has enough rows.
-	srcRecords := ReadCsvFile(stem, metadata)
-	if srcLen := len(srcRecords); srcLen <= dataIndex {
-		failRuntime(ValidationContext{File: fileName, Rule: "vtype"}, "src_records length [%d] <= data_index [%d]", srcLen, dataIndex)
-		return
-	}
-	srcFields := srcRecords[nameIndex]
-	log.Printf("src_fields: %q", srcFields)
+	// Validate metadata indices and open the source CSV file.
+	source := requiredRecordSource(ValidationContext{File: fileName, Rule: "vtype"}, stem, metadata)
+	log.Printf("src_fields: %q", source.table.Fields)
 
 	// Validate each vtype rule against the CSV data.
 	for _, vtype := range ruler {
 		// Create field expression to extract values from the specified column.
 		fieldExpr := GenerateFieldExpr(metadata, vtype.Field)
-		fieldVals := requiredFieldOccurrences(
+		fieldVals := source.occurrences(
 			fieldExpr,
 			vtype.Field,
-			srcFields,
-			srcRecords,
 			ValidationContext{File: fileName, Rule: "vtype", Field: vtype.Field},
 		)
 
+		// Compile the optional JSON Schema once per rule.
+		var schema *jsonSchema
+		if vtype.Type == "json" {
+			var err error
+			schema, err = loadJSONSchema(vtype.Schema, vtype.SchemaFile, metadata)
+			if err != nil {
+				failRuntime(ValidationContext{File: fileName, Rule: "vtype", Field: vtype.Field}, "src_field [%s] json schema is invalid: %v", vtype.Field, err)
+				return
+			}
+		}
+
 		// Cache already-checked values to avoid redundant type parsing.
 		// Map structure: field_name → { value → already_checked }
 		typedSearchedFieldCache := make(map[string]map[string]bool)
+		stats := metadata.ruleStats()
 		for occurrence := range fieldVals {
 			fieldVal := occurrence.Value
 			log.Printf("checking src_field [%s] value [%s] of type [%s]", vtype.Field, fieldVal, vtype.Type)
@@ -81,6 +76,7 @@ func VTypeTest(stem string, ruler []VType, metadata *Metadata) {
 				// Skip if this value was already validated for this field.
 				if _, ok := typedSearchedFieldCache[vtype.Field][fieldVal]; ok {
 					log.Printf("src_field [%s] value [%s] already checked", vtype.Field, fieldVal)
+					stats.cacheHit()
 					continue
 				}
 
@@ -126,6 +122,7 @@ func VTypeTest(stem string, ruler []VType, metadata *Metadata) {
 				// Skip if this value was already validated for this field.
 				if _, ok := typedSearchedFieldCache[vtype.Field][fieldVal]; ok {
 					log.Printf("src_field [%s] value [%s] already checked", vtype.Field, fieldVal)
+					stats.cacheHit()
 					continue
 				}
 
@@ -171,6 +168,7 @@ func VTypeTest(stem string, ruler []VType, metadata *Metadata) {
 				// Skip if this value was already validated for this field.
 				if _, ok := typedSearchedFieldCache[vtype.Field][fieldVal]; ok {
 					log.Printf("src_field [%s] value [%s] already checked", vtype.Field, fieldVal)
+					stats.cacheHit()
 					continue
 				}
 
@@ -191,7 +189,63 @@ func VTypeTest(stem string, ruler []VType, metadata *Metadata) {
 					return
 				}
 
+			case "json":
+				// Skip if this value was already validated for this field.
+				if _, ok := typedSearchedFieldCache[vtype.Field][fieldVal]; ok {
+					log.Printf("src_field [%s] value [%s] already checked", vtype.Field, fieldVal)
+					stats.cacheHit()
+					continue
+				}
+
+				ctx := ValidationContext{
+					File:  fileName,
+					Rule:  "vtype",
+					Field: vtype.Field,
+					Row:   rowPointer(occurrence.Row),
+					Value: fieldVal,
+				}
+				v, err := decodeJSONValue(fieldVal)
+				if err != nil {
+					failValidation(ctx, "src_field [%s] value [%s] is not valid json: %v", vtype.Field, fieldVal, err)
+					return
+				}
+				// Check the decoded document against the schema if specified.
+				if schema != nil {
+					if err := schema.validate(v, "$"); err != nil {
+						failValidation(ctx, "src_field [%s] value [%s] does not match schema: %v", vtype.Field, fieldVal, err)
+						return
+					}
+				}
+
 			default:
+				// Fall back to the built-in string format validators.
+				if validate, ok := formatValidators[vtype.Type]; ok {
+					// Skip if this value was already validated for this field.
+					if _, ok := typedSearchedFieldCache[vtype.Field][fieldVal]; ok {
+						log.Printf("src_field [%s] value [%s] already checked", vtype.Field, fieldVal)
+						stats.cacheHit()
+						continue
+					}
+
+					if !validate(fieldVal) {
+						failValidation(
+							ValidationContext{
+								File:  fileName,
+								Rule:  "vtype",
+								Field: vtype.Field,
+								Row:   rowPointer(occurrence.Row),
+								Value: fieldVal,
+							},
+							"src_field [%s] value [%s] is not a valid %s",
+							vtype.Field,
+							fieldVal,
+							vtype.Type,
+						)
+						return
+					}
+					break
+				}
+
 				failRuntime(
 					ValidationContext{
 						File:  fileName,
@@ -3,6 +3,7 @@ package csvons
 import (
 	"encoding/json"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -125,3 +126,113 @@ func TestVTypeEmployees(t *testing.T) {
 		}
 	}
 }
+
+// TestVTypeAbilities validates vtype constraints using the abilities test data.
+// Tests the json type against a schema file and a JSON path field expression.
+func TestVTypeAbilities(t *testing.T) {
+	root := projectRoot()
+	configFileName := filepath.Join(root, "ruler", "ruler_abilities.json")
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+
+	metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+
+	for stem, v := range rules {
+		rulers := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &rulers); err != nil {
+			t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+		}
+		for k, v := range rulers {
+			if k == "vtype" {
+				var vtype []VType
+				if err := json.Unmarshal(v, &vtype); err != nil {
+					t.Fatalf("error unmarshalling vtype: %v", err)
+				}
+				VTypeTest(stem, vtype, metadata)
+			}
+		}
+	}
+}
+
+// TestVTypeJSONRejects verifies invalid JSON and schema violations are reported.
+func TestVTypeJSONRejects(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+
+	writeTestCsv(t, dir, "layouts", "Layout\n\"{\"\"w\"\":1}\"\n\"{\"\"w\"\":\"\n")
+	err := expectValidationError(t, func() { VTypeTest("layouts", []VType{{Field: "Layout", Type: "json"}}, metadata) })
+	if err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "is not valid json") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	writeTestCsv(t, dir, "layouts", "Layout\n\"{\"\"w\"\":1}\"\n\"{\"\"w\"\":-1}\"\n")
+	schema := json.RawMessage(`{"type":"object","properties":{"w":{"type":"integer","minimum":0}}}`)
+	err = expectValidationError(t, func() {
+		VTypeTest("layouts", []VType{{Field: "Layout", Type: "json", Schema: schema}}, metadata)
+	})
+	if err.Row == nil || *err.Row != 3 || !strings.Contains(err.Message, "$.w: value [-1] is less than minimum [0]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+// TestVTypeJSONSchemaStrictness verifies that unsupported schema keywords and
+// type names are rejected, "const": null is enforced, trailing input after a JSON value is invalid and large integers
+// are checked by their exact value.
+func TestVTypeJSONSchemaStrictness(t *testing.T) {
+	dir := t.TempDir()
+	metadata := testMetadata(dir)
+	writeTestCsv(t, dir, "layouts", "Layout\n\"{\"\"w\"\":1}\"\n")
+
+	for _, schema := range []string{
+		`{"oneOf": [{"type": "string"}]}`,
+		`{"properties": {"w": {"$ref": "#/definitions/w"}}}`,
+		`{"additionalProperties": {"not": {"type": "string"}}}`,
+		`{"type": "object"} {}`,
+		`{"type": "strng"}`,
+		`{"properties": {"w": {"type": ["integer", "nul"]}}}`,
+	} {
+		err := expectValidationError(t, func() {
+			VTypeTest("layouts", []VType{{Field: "Layout", Type: "json", Schema: json.RawMessage(schema)}}, metadata)
+		})
+		if err.Code != 2 || !strings.Contains(err.Message, "json schema is invalid") {
+			t.Fatalf("schema %s: unexpected error: %+v", schema, err)
+		}
+	}
+	annotated := json.RawMessage(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "Layout", "type": "object"}`)
+	VTypeTest("layouts", []VType{{Field: "Layout", Type: "json", Schema: annotated}}, metadata)
+
+	writeTestCsv(t, dir, "layouts", "Layout\n\"{\"\"w\"\":1}garbage\"\n")
+	err := expectValidationError(t, func() { VTypeTest("layouts", []VType{{Field: "Layout", Type: "json"}}, metadata) })
+	if !strings.Contains(err.Message, "is not valid json") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	// The JSON path finds no value in the invalid cell, so the range is
+	// never checked.
+	VTypeTest("layouts", []VType{{Field: "Layout$.w", Type: "int", Range: &Range{Min: 5, Max: 10}}}, metadata)
+
+	// 2^53 + 1 rounds to 2^53 as a float64.
+	writeTestCsv(t, dir, "layouts", "Layout\n\"{\"\"w\"\":9007199254740993}\"\n")
+	limit := json.RawMessage(`{"properties": {"w": {"type": "integer", "maximum": 9007199254740992}}}`)
+	err = expectValidationError(t, func() {
+		VTypeTest("layouts", []VType{{Field: "Layout", Type: "json", Schema: limit}}, metadata)
+	})
+	if !strings.Contains(err.Message, "value [9007199254740993] is greater than maximum [9007199254740992]") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	enum := json.RawMessage(`{"properties": {"w": {"enum": [9007199254740993.0]}}}`)
+	VTypeTest("layouts", []VType{{Field: "Layout", Type: "json", Schema: enum}}, metadata)
+
+	// "const": null only accepts null, unlike a schema without const.
+	nullConst := json.RawMessage(`{"properties": {"w": {"const": null}}}`)
+	err = expectValidationError(t, func() {
+		VTypeTest("layouts", []VType{{Field: "Layout", Type: "json", Schema: nullConst}}, metadata)
+	})
+	if !strings.Contains(err.Message, "$.w: expected const null") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	writeTestCsv(t, dir, "layouts", "Layout\n\"{\"\"w\"\":null}\"\n")
+	VTypeTest("layouts", []VType{{Field: "Layout", Type: "json", Schema: nullConst}}, metadata)
+}
@@ -0,0 +1,437 @@
+package csvons
+
+import (
+	"bufio"
+	"cmp"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// defaultExistsMemoryLimitMB is the memory cap of the bloom and sorted
+// exists strategies when the rule sets none.
+const defaultExistsMemoryLimitMB = 64
+
+// existsMissing reports a source value that is not in the destination.
+func existsMissing(ctx ValidationContext, field FieldPair, value string, row int) {
+	ctx.Row = rowPointer(row)
+	ctx.Value = value
+	failValidation(ctx, "src_field [%s] value [%s] not found in dst_records", field.Src, value)
+}
+
+// -------------------------------------------------------
+// Bloom filter strategy
+// -------------------------------------------------------
+
+// bloomFilter is a fixed-size Bloom filter over strings, using double
+// hashing to derive its k bit positions.
+type bloomFilter struct {
+	bits []uint64
+	k    uint64
+}
+
+// newBloomFilter returns a filter of about sizeBytes bytes with the number
+// of hash functions that minimizes false positives for n values.
+func newBloomFilter(sizeBytes int64, n int) *bloomFilter {
+	words := max(sizeBytes/8, 1)
+	k := 1.0
+	if n > 0 {
+		k = math.Round(float64(words*64) / float64(n) * math.Ln2)
+	}
+	return &bloomFilter{bits: make([]uint64, words), k: uint64(min(max(k, 1), 16))}
+}
+
+// positions returns the two hashes the k bit positions derive from.
+func (b *bloomFilter) positions(value string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	h1 := h.Sum64()
+	// Derive an odd second hash so successive positions differ.
+	h2 := (h1>>33 ^ h1*0x9e3779b97f4a7c15) | 1
+	return h1, h2
+}
+
+func (b *bloomFilter) add(value string) {
+	h1, h2 := b.positions(value)
+	size := uint64(len(b.bits)) * 64
+	for i := range b.k {
+		bit := (h1 + i*h2) % size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// mayContain reports false only if value was never added.
+func (b *bloomFilter) mayContain(value string) bool {
+	h1, h2 := b.positions(value)
+	size := uint64(len(b.bits)) * 64
+	for i := range b.k {
+		bit := (h1 + i*h2) % size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// existsBloom checks one field pair with a Bloom filter of the destination
+// column. Source values the filter rejects are missing; the others are
+// collected as candidates and confirmed exactly by rescanning the
+// destination whenever they fill their half of the memory limit.
+func existsBloom(source, dst *recordSource, field FieldPair, limit int64, ctx, dstCtx ValidationContext) {
+	metadata := source.metadata
+	dstExpr := GenerateFieldExpr(metadata, field.Dst)
+
+	// Size the filter for the number of destination values.
+	n := 0
+	for range dst.occurrences(dstExpr, field.Dst, dstCtx) {
+		n++
+	}
+	filter := newBloomFilter(limit/2, n)
+	for occurrence := range dst.occurrences(dstExpr, field.Dst, dstCtx) {
+		filter.add(occurrence.Value)
+	}
+	log.Printf("dst_field [%s] bloom filter: %d values, %d bytes, k=%d", field.Dst, n, len(filter.bits)*8, filter.k)
+
+	// candidates maps each unconfirmed source value to the first row it
+	// appears in.
+	candidates := make(map[string]int)
+	var candidateBytes int64
+	confirm := func() (string, int, bool) {
+		if len(candidates) == 0 {
+			return "", 0, false
+		}
+		log.Printf("confirming %d src_field [%s] candidates against dst_records", len(candidates), field.Src)
+		for occurrence := range dst.occurrences(dstExpr, field.Dst, dstCtx) {
+			delete(candidates, occurrence.Value)
+		}
+
+		// Report the earliest false positive, like the hash strategy would.
+		missing, missingRow := "", 0
+		for value, row := range candidates {
+			if missingRow == 0 || row < missingRow {
+				missing, missingRow = value, row
+			}
+		}
+		clear(candidates)
+		candidateBytes = 0
+		return missing, missingRow, missingRow != 0
+	}
+
+	srcExpr := GenerateFieldExpr(metadata, field.Src)
+	for occurrence := range source.occurrences(srcExpr, field.Src, ctx) {
+		if !filter.mayContain(occurrence.Value) {
+			// An unconfirmed candidate from an earlier row may be missing too.
+			value, row := occurrence.Value, occurrence.Row
+			if candidate, candidateRow, ok := confirm(); ok && candidateRow < row {
+				value, row = candidate, candidateRow
+			}
+			existsMissing(ctx, field, value, row)
+			return
+		}
+
+		if _, ok := candidates[occurrence.Value]; ok {
+			continue
+		}
+		candidates[occurrence.Value] = occurrence.Row
+		candidateBytes += int64(len(occurrence.Value)) + valueIndexEntryOverhead
+		if candidateBytes >= limit/2 {
+			if value, row, ok := confirm(); ok {
+				existsMissing(ctx, field, value, row)
+				return
+			}
+		}
+	}
+
+	if value, row, ok := confirm(); ok {
+		existsMissing(ctx, field, value, row)
+	}
+}
+
+// -------------------------------------------------------
+// External sort strategy
+// -------------------------------------------------------
+
+// sortEntry is a value with the 1-based row it came from.
+type sortEntry struct {
+	value string
+	row   int
+}
+
+func compareSortEntries(a, b sortEntry) int {
+	if c := cmp.Compare(a.value, b.value); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.row, b.row)
+}
+
+// externalSorter sorts entries that may not fit in memory. Entries are
+// buffered up to a byte limit, then sorted and spilled to a run file in dir;
+// sorted merges the runs.
+type externalSorter struct {
+	dir     string
+	name    string
+	limit   int64
+	entries []sortEntry
+	bytes   int64
+	runs    []string
+}
+
+func newExternalSorter(dir, name string, limit int64) *externalSorter {
+	return &externalSorter{dir: dir, name: name, limit: limit}
+}
+
+func (s *externalSorter) add(value string, row int) error {
+	s.entries = append(s.entries, sortEntry{value: value, row: row})
+	s.bytes += int64(len(value)) + valueIndexEntryOverhead
+	if s.bytes >= s.limit {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the buffered entries, sorted, to a new run file. Each entry
+// is stored as uvarint row, uvarint value length and the value bytes.
+func (s *externalSorter) spill() error {
+	slices.SortFunc(s.entries, compareSortEntries)
+	path := filepath.Join(s.dir, fmt.Sprintf("%s-%d.run", s.name, len(s.runs)))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	var header [2 * binary.MaxVarintLen64]byte
+	for _, entry := range s.entries {
+		n := binary.PutUvarint(header[:], uint64(entry.row))
+		n += binary.PutUvarint(header[n:], uint64(len(entry.value)))
+		if _, err := w.Write(header[:n]); err != nil {
+			file.Close()
+			return err
+		}
+		if _, err := w.WriteString(entry.value); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("spilled %d %s entries to %s", len(s.entries), s.name, path)
+	s.runs = append(s.runs, path)
+	clear(s.entries)
+	s.entries = s.entries[:0]
+	s.bytes = 0
+	return nil
+}
+
+// sorted returns the added entries in value order, then row order. When
+// nothing was spilled the buffer is returned as is.
+func (s *externalSorter) sorted() (*sortedEntries, error) {
+	if len(s.runs) == 0 {
+		slices.SortFunc(s.entries, compareSortEntries)
+		return &sortedEntries{buffer: s.entries}, nil
+	}
+	if len(s.entries) > 0 {
+		if err := s.spill(); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := &sortedEntries{}
+	for _, path := range s.runs {
+		file, err := os.Open(path)
+		if err != nil {
+			merged.close()
+			return nil, err
+		}
+		run := &runReader{file: file, reader: bufio.NewReader(file)}
+		merged.files = append(merged.files, file)
+		if run.advance(); run.err != nil {
+			merged.close()
+			return nil, run.err
+		}
+		if !run.done {
+			merged.runs = append(merged.runs, run)
+		}
+	}
+	heap.Init(&merged.runs)
+	return merged, nil
+}
+
+// runReader reads the entries of one run file in order.
+type runReader struct {
+	file    *os.File
+	reader  *bufio.Reader
+	current sortEntry
+	done    bool
+	err     error
+}
+
+// advance reads the next entry into current.
+func (r *runReader) advance() {
+	row, err := binary.ReadUvarint(r.reader)
+	if err == io.EOF {
+		r.done = true
+		return
+	}
+	if err != nil {
+		r.err = err
+		return
+	}
+	size, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		r.err = err
+		return
+	}
+	value := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, value); err != nil {
+		r.err = err
+		return
+	}
+	r.current = sortEntry{value: string(value), row: int(row)}
+}
+
+// runHeap orders run readers by their current entry.
+type runHeap []*runReader
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return compareSortEntries(h[i].current, h[j].current) < 0 }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	run := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return run
+}
+
+// sortedEntries iterates the output of an externalSorter, either from its
+// in-memory buffer or by merging its run files.
+type sortedEntries struct {
+	buffer []sortEntry
+	runs   runHeap
+	files  []*os.File
+	err    error
+}
+
+// next returns the next entry, or false at the end or on a read error;
+// check err afterwards.
+func (e *sortedEntries) next() (sortEntry, bool) {
+	if e.files == nil {
+		if len(e.buffer) == 0 {
+			return sortEntry{}, false
+		}
+		entry := e.buffer[0]
+		e.buffer = e.buffer[1:]
+		return entry, true
+	}
+
+	if e.err != nil || len(e.runs) == 0 {
+		return sortEntry{}, false
+	}
+	run := e.runs[0]
+	entry := run.current
+	run.advance()
+	switch {
+	case run.err != nil:
+		e.err = run.err
+	case run.done:
+		heap.Pop(&e.runs)
+	default:
+		heap.Fix(&e.runs, 0)
+	}
+	return entry, true
+}
+
+// close releases the run files.
+func (e *sortedEntries) close() {
+	for _, file := range e.files {
+		file.Close()
+	}
+}
+
+// existsSorted checks one field pair by sorting the source and destination
+// values into run files under a temporary directory, each within half of
+// the memory limit, and merge-joining the sorted streams.
+func existsSorted(source, dst *recordSource, field FieldPair, limit int64, ctx, dstCtx ValidationContext) {
+	metadata := source.metadata
+	dir, err := os.MkdirTemp("", "csvons-exists-*")
+	if err != nil {
+		failRuntime(ctx, "error creating sort directory: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	sortColumn := func(name, fieldName string, from *recordSource, fromCtx ValidationContext) *sortedEntries {
+		sorter := newExternalSorter(dir, name, limit/2)
+		for occurrence := range from.occurrences(GenerateFieldExpr(metadata, fieldName), fieldName, fromCtx) {
+			if err := sorter.add(occurrence.Value, occurrence.Row); err != nil {
+				failRuntime(fromCtx, "error sorting [%s]: %v", fieldName, err)
+				return nil
+			}
+		}
+		entries, err := sorter.sorted()
+		if err != nil {
+			failRuntime(fromCtx, "error sorting [%s]: %v", fieldName, err)
+			return nil
+		}
+		return entries
+	}
+
+	dstEntries := sortColumn("dst", field.Dst, dst, dstCtx)
+	defer dstEntries.close()
+	checkCancelled(ctx, metadata)
+	srcEntries := sortColumn("src", field.Src, source, ctx)
+	defer srcEntries.close()
+	checkCancelled(ctx, metadata)
+
+	// Both streams are sorted by value; a source value is missing when the
+	// destination stream passes it without a match. Its first entry has the
+	// lowest row, and the lowest row over all missing values is reported.
+	var missing *sortEntry
+	merged := 0
+	dstEntry, dstOK := dstEntries.next()
+	for srcEntry, ok := srcEntries.next(); ok; srcEntry, ok = srcEntries.next() {
+		if merged%cancelCheckInterval == 0 {
+			checkCancelled(ctx, metadata)
+		}
+		merged++
+		for dstOK && dstEntry.value < srcEntry.value {
+			if merged%cancelCheckInterval == 0 {
+				checkCancelled(ctx, metadata)
+			}
+			merged++
+			dstEntry, dstOK = dstEntries.next()
+		}
+		if dstOK && dstEntry.value == srcEntry.value {
+			continue
+		}
+		if missing == nil || srcEntry.row < missing.row {
+			missing = &srcEntry
+		}
+	}
+	if srcEntries.err != nil {
+		failRuntime(ctx, "error reading sorted [%s]: %v", field.Src, srcEntries.err)
+		return
+	}
+	if dstEntries.err != nil {
+		failRuntime(dstCtx, "error reading sorted [%s]: %v", field.Dst, dstEntries.err)
+		return
+	}
+
+	if missing != nil {
+		existsMissing(ctx, field, missing.value, missing.row)
+	}
+}
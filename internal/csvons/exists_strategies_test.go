@@ -0,0 +1,162 @@
+package csvons
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// writeExistsFixture writes a destination of IDs 0..999 and a source of
+// IDs 0..499 in reverse order, with "missing-7" at row 101 and
+// "missing-3" at row 301 when withMissing is set.
+func writeExistsFixture(t *testing.T, dir string, withMissing bool) {
+	t.Helper()
+
+	var dst, src strings.Builder
+	dst.WriteString("ID\n")
+	for i := range 1000 {
+		fmt.Fprintf(&dst, "id-%d\n", i)
+	}
+	src.WriteString("Ref\n")
+	for i := range 500 {
+		value := fmt.Sprintf("id-%d", 499-i)
+		if withMissing && i == 99 {
+			value = "missing-7"
+		}
+		if withMissing && i == 299 {
+			value = "missing-3"
+		}
+		fmt.Fprintf(&src, "%s\n", value)
+	}
+	writeTestCsv(t, dir, "dst", dst.String())
+	writeTestCsv(t, dir, "src", src.String())
+}
+
+// TestExistsStrategiesAgree verifies that the bloom and sorted strategies
+// report the same first missing value as the hash strategy, including when
+// the memory limit forces candidate rescans and sort spills.
+func TestExistsStrategiesAgree(t *testing.T) {
+	dir := t.TempDir()
+	writeExistsFixture(t, dir, true)
+	metadata := testMetadata(dir)
+	field := FieldPair{Src: "Ref", Dst: "ID"}
+
+	want := expectValidationError(t, func() {
+		ExistsTest("src", []Exists{{DstFileStem: "dst", Fields: []FieldPair{field}}}, metadata)
+	})
+	if want.Row == nil || *want.Row != 101 || want.Value != "missing-7" {
+		t.Fatalf("unexpected hash error: %#v", want)
+	}
+
+	run := map[string]func(limit int64){
+		"bloom": func(limit int64) {
+			source := requiredRecordSource(ValidationContext{}, "src", metadata)
+			dst := requiredStreamSource(ValidationContext{}, "dst", metadata)
+			existsBloom(source, dst, field, limit, ValidationContext{File: "src.csv", Rule: "exists", Field: "Ref"}, ValidationContext{})
+		},
+		"sorted": func(limit int64) {
+			source := requiredRecordSource(ValidationContext{}, "src", metadata)
+			dst := requiredStreamSource(ValidationContext{}, "dst", metadata)
+			existsSorted(source, dst, field, limit, ValidationContext{File: "src.csv", Rule: "exists", Field: "Ref"}, ValidationContext{})
+		},
+	}
+	for name, fn := range run {
+		for _, limit := range []int64{2048, 1 << 20} {
+			got := expectValidationError(t, func() { fn(limit) })
+			if got.Row == nil || *got.Row != *want.Row || got.Value != want.Value || got.Message != want.Message {
+				t.Errorf("%s with limit %d: got %#v, expected %#v", name, limit, got, want)
+			}
+		}
+	}
+}
+
+// TestExistsStrategiesPass verifies that the strategies accept a source
+// whose values all exist, in memory and in streaming mode.
+func TestExistsStrategiesPass(t *testing.T) {
+	dir := t.TempDir()
+	writeExistsFixture(t, dir, false)
+
+	for _, streaming := range []bool{false, true} {
+		metadata := testMetadata(dir)
+		metadata.Streaming = streaming
+		for _, strategy := range []string{"hash", "bloom", "sorted"} {
+			ExistsTest("src", []Exists{{
+				DstFileStem:   "dst",
+				Fields:        []FieldPair{{Src: "Ref", Dst: "ID"}},
+				Strategy:      strategy,
+				MemoryLimitMB: 1,
+			}}, metadata)
+		}
+	}
+}
+
+// TestExistsStrategyOptions verifies that invalid options and a hash index
+// over the memory limit are rejected.
+func TestExistsStrategyOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeExistsFixture(t, dir, false)
+	metadata := testMetadata(dir)
+
+	got := expectValidationError(t, func() {
+		ExistsTest("src", []Exists{{DstFileStem: "dst", Fields: []FieldPair{{Src: "Ref", Dst: "ID"}}, Strategy: "btree"}}, metadata)
+	})
+	if got.ExitCode() != 2 || !strings.Contains(got.Message, "strategy [btree]") {
+		t.Fatalf("unexpected strategy error: %#v", got)
+	}
+
+	// Index 1000 values of ~6 bytes each under a 1 MiB limit: fits.
+	ExistsTest("src", []Exists{{DstFileStem: "dst", Fields: []FieldPair{{Src: "Ref", Dst: "ID"}}, MemoryLimitMB: 1}}, metadata)
+
+	got = expectValidationError(t, func() {
+		ExistsTest("src", []Exists{{DstFileStem: "dst", Fields: []FieldPair{{Src: "Ref", Dst: "ID"}}, MemoryLimitMB: -1}}, metadata)
+	})
+	if got.ExitCode() != 2 || !strings.Contains(got.Message, "memory_limit_mb") {
+		t.Fatalf("unexpected memory limit error: %#v", got)
+	}
+
+	// 30000 values take ~1.4 MiB of index.
+	var big strings.Builder
+	big.WriteString("ID\n")
+	for i := range 30000 {
+		fmt.Fprintf(&big, "id-%d\n", i)
+	}
+	writeTestCsv(t, dir, "big", big.String())
+	metadata.Indexes = NewValueIndexCache()
+	got = expectValidationError(t, func() {
+		ExistsTest("src", []Exists{{DstFileStem: "big", Fields: []FieldPair{{Src: "Ref", Dst: "ID"}}, MemoryLimitMB: 1}}, metadata)
+	})
+	if got.ExitCode() != 2 || !strings.Contains(got.Message, "use strategy bloom or sorted") {
+		t.Fatalf("unexpected index size error: %#v", got)
+	}
+	// The build stops once the index passes the limit and is not cached.
+	if got.Row == nil || *got.Row >= 30000 {
+		t.Fatalf("index build did not stop at the limit: %#v", got)
+	}
+	if hits, builds := metadata.Indexes.Stats(); hits != 0 || builds != 0 || metadata.Indexes.Bytes() != 0 {
+		t.Fatalf("oversized index was cached: hits=%d builds=%d bytes=%d", hits, builds, metadata.Indexes.Bytes())
+	}
+
+	// An index cached by a rule without a limit still fails a limited rule.
+	ExistsTest("big", []Exists{{DstFileStem: "big", Fields: []FieldPair{{Src: "ID", Dst: "ID"}}}}, metadata)
+	got = expectValidationError(t, func() {
+		ExistsTest("src", []Exists{{DstFileStem: "big", Fields: []FieldPair{{Src: "Ref", Dst: "ID"}}, MemoryLimitMB: 1}}, metadata)
+	})
+	if got.ExitCode() != 2 || !strings.Contains(got.Message, "over memory_limit_mb [1]") {
+		t.Fatalf("unexpected cached index size error: %#v", got)
+	}
+	ExistsTest("src", []Exists{{DstFileStem: "big", Fields: []FieldPair{{Src: "Ref", Dst: "ID"}}, Strategy: "sorted", MemoryLimitMB: 1}}, metadata)
+}
+
+// TestBloomFilterHasNoFalseNegatives verifies that added values are always
+// reported as possibly present.
+func TestBloomFilterHasNoFalseNegatives(t *testing.T) {
+	filter := newBloomFilter(64, 1000)
+	for i := range 1000 {
+		filter.add(fmt.Sprintf("value-%d", i))
+	}
+	for i := range 1000 {
+		if !filter.mayContain(fmt.Sprintf("value-%d", i)) {
+			t.Fatalf("value-%d was added but is not reported", i)
+		}
+	}
+}
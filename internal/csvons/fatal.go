@@ -13,6 +13,8 @@ type ValidationError struct {
 	Message  string
 	Severity string
 	Code     int
+
+	cause error // Returned by Unwrap, e.g. ErrCancelled.
 }
 
 func (e ValidationError) Error() string {
@@ -22,6 +24,12 @@ func (e ValidationError) Error() string {
 	return "csvons validation failed"
 }
 
+// Unwrap returns the error behind the failure, if any. A rule aborted by
+// cancellation wraps ErrCancelled.
+func (e ValidationError) Unwrap() error {
+	return e.cause
+}
+
 // ExitCode returns the CLI exit code that should be used when this error is
 // surfaced to callers.
 func (e ValidationError) ExitCode() int {
@@ -67,6 +75,15 @@ func failRuntime(ctx ValidationContext, format string, args ...any) {
 	panic(ctx.validationError(2, format, args...))
 }
 
+// warnValidation builds a warning-severity issue without aborting validation.
+// Rules that flag suspicious but not invalid data collect these and return them.
+func warnValidation(ctx ValidationContext, format string, args ...any) ValidationError {
+	if ctx.Severity == "" {
+		ctx.Severity = "warning"
+	}
+	return ctx.validationError(0, format, args...)
+}
+
 // failf aborts validation flow without terminating the whole process.
 // Callers can recover panic values and convert them to structured output.
 func failf(format string, args ...any) {
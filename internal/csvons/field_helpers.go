@@ -1,8 +1,10 @@
 package csvons
 
+import "iter"
+
 // requiredFieldValues validates a generated field expression and ensures
-// value extraction channel is available. It aborts via failf on invalid input.
-func requiredFieldValues(fieldExpr FieldExpr, fieldName string, fields []string, records [][]string) <-chan string {
+// the value iterator is available. It aborts via failf on invalid input.
+func requiredFieldValues(fieldExpr FieldExpr, fieldName string, fields []string, records [][]string) iter.Seq[string] {
 	if fieldExpr == nil {
 		failf("field expression [%s] is nil", fieldName)
 		return nil
@@ -15,23 +17,51 @@ func requiredFieldValues(fieldExpr FieldExpr, fieldName string, fields []string,
 	return vals
 }
 
-func requiredFieldOccurrences(fieldExpr FieldExpr, fieldName string, fields []string, records [][]string, ctx ValidationContext) <-chan FieldOccurrence {
+// requiredFieldOccurrences resolves a field expression against a table's
+// header and iterates its values over the table's data records. It aborts
+// via failRuntime when the expression cannot be resolved.
+func requiredFieldOccurrences(metadata *Metadata, fieldExpr FieldExpr, fieldName string, table *Table, ctx ValidationContext) iter.Seq[FieldOccurrence] {
 	ctx.Field = fieldName
 	if fieldExpr == nil {
 		failRuntime(ctx, "field expression [%s] is nil", fieldName)
 		return nil
 	}
 
-	provider, ok := fieldExpr.(fieldOccurrenceProvider)
+	extractor, ok := fieldExpr.(recordExtractor)
 	if !ok {
 		failRuntime(ctx, "field expression [%s] cannot resolve values", fieldName)
 		return nil
 	}
 
-	occurrences := provider.FieldOccurrences(fields, records)
+	occurrences := recordOccurrences(extractor.recordExtract(table), metadata, table.Records)
 	if occurrences == nil {
 		failRuntime(ctx, "field expression [%s] cannot resolve values", fieldName)
 		return nil
 	}
 	return occurrences
 }
+
+// requiredRowValues resolves a field expression and maps each 1-based row
+// number to the value it yields in that row.
+func requiredRowValues(metadata *Metadata, fieldName string, table *Table, ctx ValidationContext) map[int]string {
+	fieldExpr := GenerateFieldExpr(metadata, fieldName)
+	return collectRowValues(requiredFieldOccurrences(metadata, fieldExpr, fieldName, table, ctx), fieldName, ctx)
+}
+
+// collectRowValues maps each row of occurrences to its value. It aborts via
+// failRuntime when the expression yields more than one value in a row, as
+// a per-row value would otherwise silently keep only one of them.
+func collectRowValues(occurrences iter.Seq[FieldOccurrence], fieldName string, ctx ValidationContext) map[int]string {
+	ctx.Field = fieldName
+	rowValues := make(map[int]string)
+	for occurrence := range occurrences {
+		if _, ok := rowValues[occurrence.Row]; ok {
+			ctx.Row = rowPointer(occurrence.Row)
+			ctx.Value = occurrence.Value
+			failRuntime(ctx, "field expression [%s] yields more than one value in a row", fieldName)
+			return nil
+		}
+		rowValues[occurrence.Row] = occurrence.Value
+	}
+	return rowValues
+}
@@ -1,15 +1,16 @@
 package csvons
 
 import (
+	"iter"
 	"strings"
 	"testing"
 )
 
-type nilChanExpr struct{}
+type nilSeqExpr struct{}
 
-func (n *nilChanExpr) FieldValue(fields []string, records [][]string) <-chan string { return nil }
-func (n *nilChanExpr) typeString() string                                           { return "nil" }
-func (n *nilChanExpr) Init(metadata *Metadata, expr string)                         {}
+func (n *nilSeqExpr) FieldValue(fields []string, records [][]string) iter.Seq[string] { return nil }
+func (n *nilSeqExpr) typeString() string                                              { return "nil" }
+func (n *nilSeqExpr) Init(metadata *Metadata, expr string)                            {}
 
 func TestRequiredFieldValuesPanicsWhenExprNil(t *testing.T) {
 	defer func() {
@@ -25,7 +26,7 @@ func TestRequiredFieldValuesPanicsWhenExprNil(t *testing.T) {
 	requiredFieldValues(nil, "Nope", nil, nil)
 }
 
-func TestRequiredFieldValuesPanicsWhenChannelNil(t *testing.T) {
+func TestRequiredFieldValuesPanicsWhenSeqNil(t *testing.T) {
 	defer func() {
 		r := recover()
 		if r == nil {
@@ -36,5 +37,5 @@ func TestRequiredFieldValuesPanicsWhenChannelNil(t *testing.T) {
 		}
 	}()
 
-	requiredFieldValues(&nilChanExpr{}, "Nope", nil, nil)
+	requiredFieldValues(&nilSeqExpr{}, "Nope", nil, nil)
 }
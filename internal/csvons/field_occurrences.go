@@ -1,8 +1,8 @@
 package csvons
 
 import (
+	"iter"
 	"log"
-	"slices"
 	"strings"
 )
 
@@ -13,114 +13,237 @@ type FieldOccurrence struct {
 	Value string
 }
 
-type fieldOccurrenceProvider interface {
-	FieldOccurrences(fields []string, records [][]string) <-chan FieldOccurrence
+// recordExtractFunc passes each value a field expression yields for the
+// record at 0-based index i to yield. It returns false when iteration must
+// stop: yield returned false, or the remaining records must not be read.
+type recordExtractFunc func(i int, record []string, yield func(string) bool) bool
+
+// recordExtractor is implemented by field expressions that resolve their
+// columns against the header once and then extract values one record at a
+// time, so records can be streamed instead of held in memory.
+type recordExtractor interface {
+	// recordExtract returns nil if the field is not a column of table.
+	recordExtract(table *Table) recordExtractFunc
+}
+
+// recordOccurrences iterates the values extract finds in each data record,
+// with their rows. Returns nil if extract is nil. Iteration aborts via
+// failRuntime when the metadata's context is cancelled, and is counted in
+// the context's RuleStats.
+func recordOccurrences(extract recordExtractFunc, metadata *Metadata, records [][]string) iter.Seq[FieldOccurrence] {
+	if extract == nil {
+		return nil
+	}
+
+	return func(yield func(FieldOccurrence) bool) {
+		rows, values := 0, 0
+		defer func() { metadata.ruleStats().addPass(rows, values) }()
+
+		row := 0
+		emit := func(value string) bool {
+			values++
+			return yield(FieldOccurrence{Row: row, Value: value})
+		}
+		for i := metadata.DataIndex; i < len(records); i++ {
+			if (i-metadata.DataIndex)%cancelCheckInterval == 0 {
+				checkCancelled(ValidationContext{}, metadata)
+			}
+			rows++
+			row = i + 1
+			if !extract(i, records[i], emit) {
+				return
+			}
+		}
+	}
+}
+
+// recordValues iterates the values extract finds in each data record, like
+// recordOccurrences without rows.
+func recordValues(extract recordExtractFunc, metadata *Metadata, records [][]string) iter.Seq[string] {
+	if extract == nil {
+		return nil
+	}
+
+	return func(yield func(string) bool) {
+		rows, values := 0, 0
+		defer func() { metadata.ruleStats().addPass(rows, values) }()
+
+		emit := func(value string) bool {
+			values++
+			return yield(value)
+		}
+		for i := metadata.DataIndex; i < len(records); i++ {
+			if (i-metadata.DataIndex)%cancelCheckInterval == 0 {
+				checkCancelled(ValidationContext{}, metadata)
+			}
+			rows++
+			if !extract(i, records[i], emit) {
+				return
+			}
+		}
+	}
+}
+
+func (p *PlainField) FieldOccurrences(fields []string, records [][]string) iter.Seq[FieldOccurrence] {
+	return recordOccurrences(p.recordExtract(headerTable(fields)), p.metadata, records)
 }
 
-func (p *PlainField) FieldOccurrences(fields []string, records [][]string) <-chan FieldOccurrence {
-	fieldIndex := slices.Index(fields, p.fieldName)
+func (p *PlainField) recordExtract(table *Table) recordExtractFunc {
+	fieldIndex := table.Column(p.fieldName)
 	if fieldIndex == -1 {
 		return nil
 	}
 
-	output := make(chan FieldOccurrence, 128)
-	go func() {
-		defer close(output)
-		for i := p.metadata.DataIndex; i < len(records); i++ {
-			record := records[i]
-			if fieldIndex < len(record) {
-				output <- FieldOccurrence{Row: i + 1, Value: record[fieldIndex]}
-			} else {
-				log.Printf("plain field [%s] not found in record [%d]", p.fieldName, i)
+	return func(i int, record []string, yield func(string) bool) bool {
+		if fieldIndex < len(record) {
+			if !yield(record[fieldIndex]) {
+				return false
 			}
+		} else {
+			log.Printf("plain field [%s] not found in record [%d]", p.fieldName, i)
 		}
-	}()
+		return true
+	}
+}
 
-	return output
+func (r *RepeatField) FieldOccurrences(fields []string, records [][]string) iter.Seq[FieldOccurrence] {
+	return recordOccurrences(r.recordExtract(headerTable(fields)), r.metadata, records)
 }
 
-func (r *RepeatField) FieldOccurrences(fields []string, records [][]string) <-chan FieldOccurrence {
-	fieldIndex := slices.Index(fields, r.fieldName)
+func (r *RepeatField) recordExtract(table *Table) recordExtractFunc {
+	fieldIndex := table.Column(r.fieldName)
 	if fieldIndex == -1 {
 		return nil
 	}
 
-	output := make(chan FieldOccurrence, 128)
-	go func() {
-		defer close(output)
-		for i := r.metadata.DataIndex; i < len(records); i++ {
-			record := records[i]
-			if fieldIndex < len(record) {
-				lev1Vals := strings.Split(record[fieldIndex], r.metadata.Lev1Separator)
-				for _, lev1Val := range lev1Vals {
-					output <- FieldOccurrence{Row: i + 1, Value: lev1Val}
+	return func(i int, record []string, yield func(string) bool) bool {
+		if fieldIndex < len(record) {
+			lev1Vals := strings.Split(record[fieldIndex], r.metadata.Lev1Separator)
+			for _, lev1Val := range lev1Vals {
+				if !yield(lev1Val) {
+					return false
 				}
-			} else {
-				log.Printf("repeat field [%s] not found in record [%d]", r.fieldName, i)
 			}
+		} else {
+			log.Printf("repeat field [%s] not found in record [%d]", r.fieldName, i)
 		}
-	}()
+		return true
+	}
+}
 
-	return output
+func (n *NestedField) FieldOccurrences(fields []string, records [][]string) iter.Seq[FieldOccurrence] {
+	return recordOccurrences(n.recordExtract(headerTable(fields)), n.metadata, records)
 }
 
-func (n *NestedField) FieldOccurrences(fields []string, records [][]string) <-chan FieldOccurrence {
-	fieldIndex := slices.Index(fields, n.fieldName)
+func (n *NestedField) recordExtract(table *Table) recordExtractFunc {
+	fieldIndex := table.Column(n.fieldName)
 	if fieldIndex == -1 {
 		return nil
 	}
 
-	output := make(chan FieldOccurrence, 128)
-	go func() {
-		defer close(output)
-		for i := n.metadata.DataIndex; i < len(records); i++ {
-			record := records[i]
-			if fieldIndex < len(record) {
-				lev1Vals := strings.Split(record[fieldIndex], n.metadata.Lev1Separator)
-				for _, lev1Val := range lev1Vals {
-					lev2Vals := strings.Split(lev1Val, n.metadata.Lev2Separator)
-					if n.index < len(lev2Vals) {
-						output <- FieldOccurrence{Row: i + 1, Value: lev2Vals[n.index]}
-					} else {
-						log.Printf("nested field [%s] level 2 value [%s] length [%d] not found in record [%d]", n.fieldName, lev1Val, len(lev2Vals), i)
+	return func(i int, record []string, yield func(string) bool) bool {
+		if fieldIndex < len(record) {
+			lev1Vals := strings.Split(record[fieldIndex], n.metadata.Lev1Separator)
+			for _, lev1Val := range lev1Vals {
+				lev2Vals := strings.Split(lev1Val, n.metadata.Lev2Separator)
+				if n.index < len(lev2Vals) {
+					if !yield(lev2Vals[n.index]) {
+						return false
 					}
+				} else {
+					log.Printf("nested field [%s] level 2 value [%s] length [%d] not found in record [%d]", n.fieldName, lev1Val, len(lev2Vals), i)
 				}
-			} else {
-				log.Printf("nested field [%s] not found in record [%d]", n.fieldName, i)
 			}
+		} else {
+			log.Printf("nested field [%s] not found in record [%d]", n.fieldName, i)
 		}
-	}()
+		return true
+	}
+}
 
-	return output
+func (c *ComplexField) FieldOccurrences(fields []string, records [][]string) iter.Seq[FieldOccurrence] {
+	return recordOccurrences(c.recordExtract(headerTable(fields)), c.metadata, records)
 }
 
-func (c *ComplexField) FieldOccurrences(fields []string, records [][]string) <-chan FieldOccurrence {
+func (c *ComplexField) recordExtract(table *Table) recordExtractFunc {
 	fieldIndexes := make([]int, len(c.fieldNames))
 	for i, fieldName := range c.fieldNames {
-		fieldIndexes[i] = slices.Index(fields, fieldName)
+		fieldIndexes[i] = table.Column(fieldName)
 		if fieldIndexes[i] == -1 {
 			log.Printf("complex field [%s] not found in fields", fieldName)
 			return nil
 		}
 	}
 
-	output := make(chan FieldOccurrence, 128)
-	go func() {
-		defer close(output)
-		for i := c.metadata.DataIndex; i < len(records); i++ {
-			record := records[i]
-			cpxStr := ""
-			for idx, fieldIndex := range fieldIndexes {
-				if fieldIndex < len(record) {
-					cpxStr += record[fieldIndex] + c.metadata.FieldConnector
-				} else {
-					log.Printf("complex field [%s] not found in record [%d]", c.fieldNames[idx], i)
-					return
+	return func(i int, record []string, yield func(string) bool) bool {
+		var cpx strings.Builder
+		for idx, fieldIndex := range fieldIndexes {
+			if fieldIndex < len(record) {
+				cpx.WriteString(record[fieldIndex])
+				cpx.WriteString(c.metadata.FieldConnector)
+			} else {
+				log.Printf("complex field [%s] not found in record [%d]", c.fieldNames[idx], i)
+				return false
+			}
+		}
+		return yield(cpx.String())
+	}
+}
+
+func (j *JSONField) FieldOccurrences(fields []string, records [][]string) iter.Seq[FieldOccurrence] {
+	return recordOccurrences(j.recordExtract(headerTable(fields)), j.metadata, records)
+}
+
+func (j *JSONField) recordExtract(table *Table) recordExtractFunc {
+	fieldIndex := table.Column(j.fieldName)
+	if fieldIndex == -1 {
+		return nil
+	}
+
+	return func(i int, record []string, yield func(string) bool) bool {
+		if fieldIndex < len(record) {
+			if value, ok := j.lookup(record[fieldIndex]); ok {
+				if !yield(value) {
+					return false
 				}
+			} else {
+				log.Printf("json field [%s] path not found in record [%d]", j.fieldName, i)
 			}
-			output <- FieldOccurrence{Row: i + 1, Value: cpxStr}
+		} else {
+			log.Printf("json field [%s] not found in record [%d]", j.fieldName, i)
 		}
-	}()
+		return true
+	}
+}
+
+func (m *MapField) FieldOccurrences(fields []string, records [][]string) iter.Seq[FieldOccurrence] {
+	return recordOccurrences(m.recordExtract(headerTable(fields)), m.metadata, records)
+}
+
+func (m *MapField) recordExtract(table *Table) recordExtractFunc {
+	fieldIndex := table.Column(m.fieldName)
+	if fieldIndex == -1 {
+		return nil
+	}
 
-	return output
+	return func(i int, record []string, yield func(string) bool) bool {
+		if fieldIndex < len(record) {
+			found := false
+			for _, entry := range splitMapCell(record[fieldIndex], m.metadata) {
+				if entry.ok && entry.key == m.key {
+					if !yield(entry.value) {
+						return false
+					}
+					found = true
+					break
+				}
+			}
+			if !found {
+				log.Printf("map field [%s] key [%s] not found in record [%d]", m.fieldName, m.key, i)
+			}
+		} else {
+			log.Printf("map field [%s] not found in record [%d]", m.fieldName, i)
+		}
+		return true
+	}
 }
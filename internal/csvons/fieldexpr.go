@@ -1,9 +1,10 @@
 package csvons
 
 import (
+	"encoding/json"
+	"iter"
 	"log"
 	"regexp"
-	"slices"
 	"strconv"
 	"strings"
 )
@@ -12,16 +13,18 @@ import (
 // A field expression defines how to extract values from CSV records
 // based on a column name and optional nested data access pattern.
 //
-// There are four implementations:
+// There are six implementations:
 //   - PlainField: direct column reference (e.g., "Username")
 //   - RepeatField: array expansion (e.g., "Tags[]")
 //   - NestedField: second-level array index (e.g., "marks{1}")
 //   - ComplexField: multi-field concatenation (e.g., "{data}{key}")
+//   - JSONField: JSON-path access into JSON cells (e.g., "Params$.damage.base")
+//   - MapField: key-value map access by key (e.g., "stats[atk]")
 type FieldExpr interface {
-	// FieldValue returns a channel that yields extracted values from the given records.
+	// FieldValue returns an iterator over the values extracted from the given records.
 	// The fields parameter contains column names (header row), and records is the full CSV data.
 	// Returns nil if the target field is not found in the column names.
-	FieldValue(fields []string, records [][]string) <-chan string
+	FieldValue(fields []string, records [][]string) iter.Seq[string]
 
 	// typeString returns a human-readable identifier for this field expression type.
 	typeString() string
@@ -44,28 +47,8 @@ type PlainField struct {
 
 // FieldValue yields one value per data row from the column matching fieldName.
 // Returns nil if the field name does not exist in the column headers.
-func (p *PlainField) FieldValue(fields []string, records [][]string) <-chan string {
-	// Find the index of the target column in the header row.
-	fieldIndex := slices.Index(fields, p.fieldName)
-	if fieldIndex == -1 {
-		return nil
-	}
-
-	output := make(chan string, 128)
-	go func() {
-		defer close(output)
-		// Iterate from the data start index through all records.
-		for i := p.metadata.DataIndex; i < len(records); i++ {
-			record := records[i]
-			if fieldIndex < len(record) {
-				output <- record[fieldIndex]
-			} else {
-				log.Printf("plain field [%s] not found in record [%d]", p.fieldName, i)
-			}
-		}
-	}()
-
-	return output
+func (p *PlainField) FieldValue(fields []string, records [][]string) iter.Seq[string] {
+	return recordValues(p.recordExtract(headerTable(fields)), p.metadata, records)
 }
 
 // typeString returns "plain" to identify this as a plain field expression.
@@ -97,30 +80,8 @@ type RepeatField struct {
 
 // FieldValue splits each cell value by Lev1Separator and yields individual elements.
 // Returns nil if the field name does not exist in the column headers.
-func (r *RepeatField) FieldValue(fields []string, records [][]string) <-chan string {
-	fieldIndex := slices.Index(fields, r.fieldName)
-	if fieldIndex == -1 {
-		return nil
-	}
-
-	output := make(chan string, 128)
-	go func() {
-		defer close(output)
-		for i := r.metadata.DataIndex; i < len(records); i++ {
-			record := records[i]
-			if fieldIndex < len(record) {
-				// Split the cell value by the first-level separator.
-				lev1Vals := strings.Split(record[fieldIndex], r.metadata.Lev1Separator)
-				for _, lev1Val := range lev1Vals {
-					output <- lev1Val
-				}
-			} else {
-				log.Printf("repeat field [%s] not found in record [%d]", r.fieldName, i)
-			}
-		}
-	}()
-
-	return output
+func (r *RepeatField) FieldValue(fields []string, records [][]string) iter.Seq[string] {
+	return recordValues(r.recordExtract(headerTable(fields)), r.metadata, records)
 }
 
 // typeString returns "repeat" to identify this as a repeat field expression.
@@ -155,36 +116,8 @@ type NestedField struct {
 
 // FieldValue yields values at the nested index from each split cell value.
 // Returns nil if the field name does not exist in the column headers.
-func (n *NestedField) FieldValue(fields []string, records [][]string) <-chan string {
-	fieldIndex := slices.Index(fields, n.fieldName)
-	if fieldIndex == -1 {
-		return nil
-	}
-
-	output := make(chan string, 128)
-	go func() {
-		defer close(output)
-		for i := n.metadata.DataIndex; i < len(records); i++ {
-			record := records[i]
-			if fieldIndex < len(record) {
-				// Split by first-level separator into groups.
-				lev1Vals := strings.Split(record[fieldIndex], n.metadata.Lev1Separator)
-				for _, lev1Val := range lev1Vals {
-					// Split each group by second-level separator and extract by index.
-					lev2Vals := strings.Split(lev1Val, n.metadata.Lev2Separator)
-					if n.index < len(lev2Vals) {
-						output <- lev2Vals[n.index]
-					} else {
-						log.Printf("nested field [%s] level 2 value [%s] length [%d] not found in record [%d]", n.fieldName, lev1Val, len(lev2Vals), i)
-					}
-				}
-			} else {
-				log.Printf("nested field [%s] not found in record [%d]", n.fieldName, i)
-			}
-		}
-	}()
-
-	return output
+func (n *NestedField) FieldValue(fields []string, records [][]string) iter.Seq[string] {
+	return recordValues(n.recordExtract(headerTable(fields)), n.metadata, records)
 }
 
 // typeString returns "nested" to identify this as a nested field expression.
@@ -224,37 +157,8 @@ type ComplexField struct {
 
 // FieldValue concatenates values from multiple columns for each row.
 // Returns nil if any of the required field names are not found in the column headers.
-func (c *ComplexField) FieldValue(fields []string, records [][]string) <-chan string {
-	// Resolve indices for all required field names.
-	fieldIndexes := make([]int, len(c.fieldNames))
-	for i, fieldName := range c.fieldNames {
-		fieldIndexes[i] = slices.Index(fields, fieldName)
-		if fieldIndexes[i] == -1 {
-			log.Printf("complex field [%s] not found in fields", fieldName)
-			return nil
-		}
-	}
-
-	output := make(chan string, 128)
-	go func() {
-		defer close(output)
-		for i := c.metadata.DataIndex; i < len(records); i++ {
-			cpxStr := ""
-			for _, fieldIndex := range fieldIndexes {
-				record := records[i]
-				if fieldIndex < len(record) {
-					// Append each field value with the connector separator.
-					cpxStr += record[fieldIndex] + c.metadata.FieldConnector
-				} else {
-					log.Printf("complex field [%s] not found in record [%d]", c.fieldNames[fieldIndex], i)
-					return
-				}
-			}
-			output <- cpxStr
-		}
-	}()
-
-	return output
+func (c *ComplexField) FieldValue(fields []string, records [][]string) iter.Seq[string] {
+	return recordValues(c.recordExtract(headerTable(fields)), c.metadata, records)
 }
 
 // typeString returns "complex" to identify this as a complex field expression.
@@ -270,21 +174,189 @@ func (c *ComplexField) Init(metadata *Metadata, expr string) {
 	c.fieldNames = regexp.MustCompile(`([a-zA-Z0-9]+)`).FindAllString(expr, -1)
 }
 
+// -------------------------------------------------------
+// MapField: key-value map access by key.
+// Example expressions: "stats[atk]", "Resist[fire]"
+// Splits by lev1_separator into entries, then by map_separator into key and value.
+// -------------------------------------------------------
+
+// MapField extracts the value stored under a key in key-value map cells.
+// Cell values are split by Lev1Separator into entries, and each entry is
+// split once by MapSeparator (default "=") into key and value.
+//
+// For example, with data "atk=10;def=5" and expression "stats[def]" → yields "5".
+// Rows without the key yield nothing.
+type MapField struct {
+	metadata  *Metadata // CSV metadata for data index and separators.
+	fieldName string    // Column name (without the "[key]" suffix).
+	key       string    // Map key to look up.
+}
+
+// FieldValue yields the value of the key from each row's map cell.
+// Returns nil if the field name does not exist in the column headers.
+func (m *MapField) FieldValue(fields []string, records [][]string) iter.Seq[string] {
+	return recordValues(m.recordExtract(headerTable(fields)), m.metadata, records)
+}
+
+// typeString returns "map" to identify this as a map field expression.
+func (m *MapField) typeString() string {
+	return "map"
+}
+
+// Init parses the expression to extract the field name and key.
+// Expression format: "fieldName[key]" (e.g., "stats[atk]").
+func (m *MapField) Init(metadata *Metadata, expr string) {
+	m.metadata = metadata
+	matches := regexp.MustCompile(`^([a-zA-Z0-9]+)\[([a-zA-Z0-9_]+)\]$`).FindStringSubmatch(expr)
+	if len(matches) != 3 {
+		return
+	}
+
+	m.fieldName = matches[1]
+	m.key = matches[2]
+}
+
+// mapEntry is one key=value entry of a map cell.
+type mapEntry struct {
+	key   string
+	value string
+	ok    bool // false if the entry has no map separator.
+}
+
+// splitMapCell splits a map cell into its entries, in cell order.
+// An empty cell has no entries.
+func splitMapCell(cell string, metadata *Metadata) []mapEntry {
+	if cell == "" {
+		return nil
+	}
+	separator := metadata.MapSeparator
+	if separator == "" {
+		separator = "="
+	}
+
+	var entries []mapEntry
+	for _, lev1Val := range strings.Split(cell, metadata.Lev1Separator) {
+		key, value, ok := strings.Cut(lev1Val, separator)
+		entries = append(entries, mapEntry{key: key, value: value, ok: ok})
+	}
+	return entries
+}
+
+// -------------------------------------------------------
+// JSONField: JSON-path access into JSON cells.
+// Example expressions: "Params$.damage.base", "Layout$.slots[0]"
+// Parses each cell as JSON and yields the value at the path.
+// -------------------------------------------------------
+
+// JSONField extracts values from cells that hold JSON documents.
+// The expression is a column name, a "$" and a path of ".key" and "[index]"
+// steps. Strings are yielded without quotes; numbers, booleans and null
+// as their JSON text; objects and arrays as compact JSON.
+//
+// For example, with data `{"damage":{"base":10}}` and expression
+// "Params$.damage.base" → yields "10".
+type JSONField struct {
+	metadata  *Metadata // CSV metadata for data index and separators.
+	fieldName string    // Column name (before the "$").
+	path      []any     // Path steps: string keys and int indices.
+}
+
+// FieldValue yields the value at the JSON path from each row's cell.
+// Returns nil if the field name does not exist in the column headers.
+func (j *JSONField) FieldValue(fields []string, records [][]string) iter.Seq[string] {
+	return recordValues(j.recordExtract(headerTable(fields)), j.metadata, records)
+}
+
+// typeString returns "json" to identify this as a JSON path field expression.
+func (j *JSONField) typeString() string {
+	return "json"
+}
+
+// Init parses the expression into the column name and path steps.
+// Expression format: "fieldName$.key[index]..." (e.g., "Params$.damage.base").
+func (j *JSONField) Init(metadata *Metadata, expr string) {
+	j.metadata = metadata
+	name, path, _ := strings.Cut(expr, "$")
+	j.fieldName = name
+	j.path = nil
+	for _, step := range regexp.MustCompile(`\.([a-zA-Z0-9_]+)|\[(\d+)\]`).FindAllStringSubmatch(path, -1) {
+		if step[1] != "" {
+			j.path = append(j.path, step[1])
+			continue
+		}
+		index, _ := strconv.Atoi(step[2])
+		j.path = append(j.path, index)
+	}
+}
+
+// lookup decodes a JSON cell and returns the rendered value at the path.
+func (j *JSONField) lookup(cell string) (string, bool) {
+	value, err := decodeJSONValue(cell)
+	if err != nil {
+		return "", false
+	}
+
+	for _, step := range j.path {
+		switch key := step.(type) {
+		case string:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			if value, ok = object[key]; !ok {
+				return "", false
+			}
+		case int:
+			array, ok := value.([]any)
+			if !ok || key >= len(array) {
+				return "", false
+			}
+			value = array[key]
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+}
+
 // -------------------------------------------------------
 // Field Expression Factory
 // -------------------------------------------------------
 
-// fieldExprMap maps regex patterns to constructor functions for each field expression type.
+// fieldExprPattern pairs a regex pattern with the constructor of the field
+// expression type it identifies.
+type fieldExprPattern struct {
+	pattern *regexp.Regexp
+	make    func(string) FieldExpr
+}
+
+// fieldExprPatterns lists the field expression types in matching order.
 // The patterns are matched against the raw field expression string to determine its type:
 //   - `^[a-zA-Z0-9]+$`          → PlainField  (e.g., "Username")
 //   - `^[a-zA-Z0-9]+\[\]$`      → RepeatField (e.g., "Tags[]")
 //   - `^[a-zA-Z0-9]+\{\d+\}$`   → NestedField (e.g., "marks{1}")
 //   - `^\{[a-zA-Z0-9]+\}+$`     → ComplexField (e.g., "{data}")
-var fieldExprMap = map[string]func(string) FieldExpr{
-	`^[a-zA-Z0-9]+$`:        func(string) FieldExpr { return &PlainField{} },
-	`^[a-zA-Z0-9]+\[\]$`:    func(string) FieldExpr { return &RepeatField{} },
-	`^[a-zA-Z0-9]+\{\d+\}$`: func(string) FieldExpr { return &NestedField{} },
-	`^\{[a-zA-Z0-9]+\}+$`:   func(string) FieldExpr { return &ComplexField{} },
+//   - `^[a-zA-Z0-9]+\$(\.[a-zA-Z0-9_]+|\[\d+\])*$` → JSONField (e.g., "Params$.damage.base")
+//   - `^[a-zA-Z0-9]+\[[a-zA-Z0-9_]+\]$` → MapField (e.g., "stats[atk]")
+//
+// The patterns do not overlap; the order only makes matching deterministic.
+var fieldExprPatterns = []fieldExprPattern{
+	{regexp.MustCompile(`^[a-zA-Z0-9]+$`), func(string) FieldExpr { return &PlainField{} }},
+	{regexp.MustCompile(`^[a-zA-Z0-9]+\[\]$`), func(string) FieldExpr { return &RepeatField{} }},
+	{regexp.MustCompile(`^[a-zA-Z0-9]+\{\d+\}$`), func(string) FieldExpr { return &NestedField{} }},
+	{regexp.MustCompile(`^\{[a-zA-Z0-9]+\}+$`), func(string) FieldExpr { return &ComplexField{} }},
+	{regexp.MustCompile(`^[a-zA-Z0-9]+\$(\.[a-zA-Z0-9_]+|\[\d+\])*$`), func(string) FieldExpr { return &JSONField{} }},
+	{regexp.MustCompile(`^[a-zA-Z0-9]+\[[a-zA-Z0-9_]+\]$`), func(string) FieldExpr { return &MapField{} }},
 }
 
 // GenerateFieldExpr creates and initializes a FieldExpr from a raw expression string.
@@ -303,9 +375,9 @@ func GenerateFieldExpr(metadata *Metadata, fieldExpr string) FieldExpr {
 	}
 
 	// Try each registered pattern to find a matching field expression type.
-	for pattern, makeFunc := range fieldExprMap {
-		if match, _ := regexp.MatchString(pattern, fieldExpr); match {
-			t := makeFunc(fieldExpr)
+	for _, p := range fieldExprPatterns {
+		if p.pattern.MatchString(fieldExpr) {
+			t := p.make(fieldExpr)
 			t.Init(metadata, fieldExpr)
 			return t
 		}
@@ -0,0 +1,99 @@
+package csvons
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchmarkRows is the number of data rows of the benchmark table.
+const benchmarkRows = 100000
+
+// benchmarkTable returns a header and records with one column per field
+// expression type.
+func benchmarkTable() ([]string, [][]string) {
+	fields := []string{"ID", "Tags", "Pairs", "Params", "Attrs"}
+	records := make([][]string, 0, benchmarkRows+1)
+	records = append(records, fields)
+	for i := range benchmarkRows {
+		records = append(records, []string{
+			fmt.Sprintf("id-%d", i),
+			fmt.Sprintf("a%d;b%d;c%d", i, i, i),
+			fmt.Sprintf("%d:x;%d:y", i, i+1),
+			fmt.Sprintf(`{"damage":{"base":%d}}`, i),
+			fmt.Sprintf("hp=%d;mp=%d", i, i*2),
+		})
+	}
+	return fields, records
+}
+
+var benchmarkExprs = []struct {
+	name    string
+	expr    string
+	newExpr func() FieldExpr
+}{
+	{"Plain", "ID", func() FieldExpr { return &PlainField{} }},
+	{"Repeat", "Tags[]", func() FieldExpr { return &RepeatField{} }},
+	{"Nested", "Pairs{0}", func() FieldExpr { return &NestedField{} }},
+	{"Complex", "{ID}{Tags}", func() FieldExpr { return &ComplexField{} }},
+	{"JSON", "Params$.damage.base", func() FieldExpr { return &JSONField{} }},
+	{"Map", "Attrs[mp]", func() FieldExpr { return &MapField{} }},
+}
+
+// newBenchmarkExpr initializes the expression of a benchmarkExprs entry.
+func newBenchmarkExpr(metadata *Metadata, newExpr func() FieldExpr, expr string) FieldExpr {
+	fieldExpr := newExpr()
+	fieldExpr.Init(metadata, expr)
+	return fieldExpr
+}
+
+func benchmarkMetadata() *Metadata {
+	return &Metadata{
+		NameIndex:      0,
+		DataIndex:      1,
+		Lev1Separator:  ";",
+		Lev2Separator:  ":",
+		FieldConnector: "|",
+	}
+}
+
+func BenchmarkFieldValue(b *testing.B) {
+	fields, records := benchmarkTable()
+	metadata := benchmarkMetadata()
+	for _, bench := range benchmarkExprs {
+		expr := newBenchmarkExpr(metadata, bench.newExpr, bench.expr)
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				n := 0
+				for range expr.FieldValue(fields, records) {
+					n++
+				}
+				if n == 0 {
+					b.Fatalf("%s yielded no values", bench.expr)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFieldOccurrences(b *testing.B) {
+	fields, records := benchmarkTable()
+	metadata := benchmarkMetadata()
+	table := headerTable(fields)
+	table.Records = records
+	for _, bench := range benchmarkExprs {
+		expr := newBenchmarkExpr(metadata, bench.newExpr, bench.expr)
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				n := 0
+				for range requiredFieldOccurrences(metadata, expr, bench.expr, table, ValidationContext{}) {
+					n++
+				}
+				if n == 0 {
+					b.Fatalf("%s yielded no values", bench.expr)
+				}
+			}
+		})
+	}
+}
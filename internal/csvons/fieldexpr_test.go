@@ -258,6 +258,117 @@ func TestComplexField_Init(t *testing.T) {
 	}
 }
 
+// TestJSONField_FieldValue verifies that JSONField yields the value at the
+// JSON path and skips cells where the path does not resolve.
+func TestJSONField_FieldValue(t *testing.T) {
+	metadata := &Metadata{DataIndex: 1}
+
+	fields := []string{"Params"}
+	records := [][]string{
+		{"header1"},
+		{`{"damage":{"base":10},"tags":["a","b"]}`},
+		{`{"damage":{"base":1.50}}`},
+		{`{"damage":{}}`},
+		{`not json`},
+	}
+
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{"Params$.damage.base", []string{"10", "1.50"}},
+		{"Params$.tags[1]", []string{"b"}},
+		{"Params$.damage", []string{`{"base":10}`, `{"base":1.50}`, `{}`}},
+	}
+
+	for _, tt := range tests {
+		field := &JSONField{}
+		field.Init(metadata, tt.expr)
+
+		var results []string
+		for val := range field.FieldValue(fields, records) {
+			results = append(results, val)
+		}
+		if len(results) != len(tt.expected) {
+			t.Errorf("JSONField(%s).FieldValue() = %q, expected %q", tt.expr, results, tt.expected)
+			continue
+		}
+		for i, val := range results {
+			if val != tt.expected[i] {
+				t.Errorf("JSONField(%s).FieldValue() result[%d] = %v, expected %v", tt.expr, i, val, tt.expected[i])
+			}
+		}
+	}
+}
+
+// TestJSONField_Init verifies that Init correctly parses the field name and path.
+func TestJSONField_Init(t *testing.T) {
+	metadata := &Metadata{DataIndex: 1}
+	field := &JSONField{}
+	field.Init(metadata, "Layout$.slots[2].id")
+
+	if field.fieldName != "Layout" {
+		t.Errorf("JSONField.Init() fieldName = %v, expected Layout", field.fieldName)
+	}
+	expected := []any{"slots", 2, "id"}
+	if len(field.path) != len(expected) {
+		t.Fatalf("JSONField.Init() path = %v, expected %v", field.path, expected)
+	}
+	for i, step := range field.path {
+		if step != expected[i] {
+			t.Errorf("JSONField.Init() path[%d] = %v, expected %v", i, step, expected[i])
+		}
+	}
+}
+
+// TestMapField_FieldValue verifies that MapField yields the value stored
+// under its key and skips rows without the key.
+func TestMapField_FieldValue(t *testing.T) {
+	metadata := &Metadata{
+		DataIndex:     1,
+		Lev1Separator: ";",
+	}
+
+	field := &MapField{}
+	field.Init(metadata, "stats[def]")
+
+	fields := []string{"stats"}
+	records := [][]string{
+		{"header1"},
+		{"atk=10;def=5"},
+		{"atk=3"},
+		{"def=a=b"},
+	}
+
+	var results []string
+	for val := range field.FieldValue(fields, records) {
+		results = append(results, val)
+	}
+
+	expected := []string{"5", "a=b"}
+	if len(results) != len(expected) {
+		t.Fatalf("MapField.FieldValue() = %q, expected %q", results, expected)
+	}
+	for i, val := range results {
+		if val != expected[i] {
+			t.Errorf("MapField.FieldValue() result[%d] = %v, expected %v", i, val, expected[i])
+		}
+	}
+}
+
+// TestMapField_Init verifies that Init correctly parses the field name and key.
+func TestMapField_Init(t *testing.T) {
+	field := &MapField{}
+	field.Init(&Metadata{}, "stats[atk_bonus]")
+
+	if field.fieldName != "stats" {
+		t.Errorf("MapField.Init() fieldName = %v, expected stats", field.fieldName)
+	}
+	if field.key != "atk_bonus" {
+		t.Errorf("MapField.Init() key = %v, expected atk_bonus", field.key)
+	}
+}
+
 // TestGenerateFieldExpr uses table-driven tests to verify that GenerateFieldExpr
 // correctly identifies and creates the appropriate FieldExpr type for each expression pattern.
 func TestGenerateFieldExpr(t *testing.T) {
@@ -364,6 +475,30 @@ func TestGenerateFieldExpr(t *testing.T) {
 			expectedType: "complex",
 			shouldBeNil:  false,
 		},
+		{
+			name:         "JSON path field expression",
+			fieldExpr:    "Params$.damage.base",
+			expectedType: "json",
+			shouldBeNil:  false,
+		},
+		{
+			name:         "JSON path field with index",
+			fieldExpr:    "Params$.tags[0]",
+			expectedType: "json",
+			shouldBeNil:  false,
+		},
+		{
+			name:         "Map field expression",
+			fieldExpr:    "stats[atk]",
+			expectedType: "map",
+			shouldBeNil:  false,
+		},
+		{
+			name:         "JSON root field expression",
+			fieldExpr:    "Params$",
+			expectedType: "json",
+			shouldBeNil:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -403,16 +538,20 @@ func TestFieldExprInterface(t *testing.T) {
 	var _ FieldExpr = (*RepeatField)(nil)
 	var _ FieldExpr = (*NestedField)(nil)
 	var _ FieldExpr = (*ComplexField)(nil)
+	var _ FieldExpr = (*JSONField)(nil)
+	var _ FieldExpr = (*MapField)(nil)
 }
 
-// TestFieldExprMap verifies that fieldExprMap contains the expected regex patterns
-// and that each constructor creates an instance of the correct type.
-func TestFieldExprMap(t *testing.T) {
+// TestFieldExprPatterns verifies that fieldExprPatterns lists the expected
+// regex patterns in order, each creating the correct field expression type.
+func TestFieldExprPatterns(t *testing.T) {
 	expectedPatterns := []string{
 		`^[a-zA-Z0-9]+$`,
 		`^[a-zA-Z0-9]+\[\]$`,
 		`^[a-zA-Z0-9]+\{\d+\}$`,
 		`^\{[a-zA-Z0-9]+\}+$`,
+		`^[a-zA-Z0-9]+\$(\.[a-zA-Z0-9_]+|\[\d+\])*$`,
+		`^[a-zA-Z0-9]+\[[a-zA-Z0-9_]+\]$`,
 	}
 
 	expectedTypes := []string{
@@ -420,20 +559,23 @@ func TestFieldExprMap(t *testing.T) {
 		"repeat",
 		"nested",
 		"complex",
+		"json",
+		"map",
 	}
 
-	for i, pattern := range expectedPatterns {
-		constructor, exists := fieldExprMap[pattern]
-		if !exists {
-			t.Errorf("fieldExprMap missing pattern: %v", pattern)
-			continue
+	if len(fieldExprPatterns) != len(expectedPatterns) {
+		t.Fatalf("fieldExprPatterns has %d entries, expected %d", len(fieldExprPatterns), len(expectedPatterns))
+	}
+	for i, p := range fieldExprPatterns {
+		if p.pattern.String() != expectedPatterns[i] {
+			t.Errorf("fieldExprPatterns[%d] pattern = %v, expected %v", i, p.pattern, expectedPatterns[i])
 		}
 
 		// Verify the constructor creates the right type.
-		instance := constructor("test")
+		instance := p.make("test")
 		actualType := instance.typeString()
 		if actualType != expectedTypes[i] {
-			t.Errorf("fieldExprMap[%v] creates type %v, expected %v", pattern, actualType, expectedTypes[i])
+			t.Errorf("fieldExprPatterns[%d] creates type %v, expected %v", i, actualType, expectedTypes[i])
 		}
 	}
 }
@@ -0,0 +1,359 @@
+package csvons
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonSchema is a compiled subset of JSON Schema used by the "json" vtype.
+//
+// Supported keywords: type, enum, const, properties, required,
+// additionalProperties (boolean or schema), items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum and
+// exclusiveMaximum (numeric form). The annotations $schema, $id, $comment,
+// title, description, default and examples are accepted and ignored; any
+// other keyword ($ref, oneOf, anyOf, allOf, not, if, format, ...) is
+// rejected, so a schema never silently accepts what it means to forbid.
+//
+// Numbers are kept as json.Number and compared exactly, so integers beyond
+// float64 precision are checked by their exact value.
+type jsonSchema struct {
+	Type                 jsonSchemaTypes        `json:"type"`
+	Enum                 []any                  `json:"enum"`
+	Const                json.RawMessage        `json:"const"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	Minimum              *json.Number           `json:"minimum"`
+	Maximum              *json.Number           `json:"maximum"`
+	ExclusiveMinimum     *json.Number           `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *json.Number           `json:"exclusiveMaximum"`
+
+	// Annotations, which do not affect validation.
+	SchemaURI   json.RawMessage `json:"$schema"`
+	ID          json.RawMessage `json:"$id"`
+	Comment     json.RawMessage `json:"$comment"`
+	Title       json.RawMessage `json:"title"`
+	Description json.RawMessage `json:"description"`
+	Default     json.RawMessage `json:"default"`
+	Examples    json.RawMessage `json:"examples"`
+
+	pattern         *regexp.Regexp // Compiled Pattern.
+	constValue      any            // Decoded Const.
+	hasConst        bool           // Const is set, possibly to null.
+	noAdditional    bool           // additionalProperties is false.
+	additionalProps *jsonSchema    // additionalProperties is a schema.
+}
+
+// jsonSchemaTypeNames lists the type names jsonTypeMatches understands.
+var jsonSchemaTypeNames = []string{"null", "boolean", "string", "number", "integer", "array", "object"}
+
+// jsonSchemaTypes accepts both "type": "string" and "type": ["string", "null"].
+type jsonSchemaTypes []string
+
+func (t *jsonSchemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = jsonSchemaTypes{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*t = multiple
+	return nil
+}
+
+// loadJSONSchema compiles an inline schema, or reads schemaFile when the
+// inline schema is empty. A relative schemaFile is resolved against the
+// ruler's directory. It returns (nil, nil) when neither is set.
+func loadJSONSchema(inline json.RawMessage, schemaFile string, metadata *Metadata) (*jsonSchema, error) {
+	data := []byte(inline)
+	if len(bytes.TrimSpace(data)) == 0 {
+		if schemaFile == "" {
+			return nil, nil
+		}
+		var err error
+		data, err = os.ReadFile(resolveRulerPath(schemaFile, metadata))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var schema jsonSchema
+	if err := decodeJSONSchema(data, &schema); err != nil {
+		return nil, err
+	}
+	if err := schema.compile(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// decodeJSONSchema decodes one schema document, rejecting unsupported
+// keywords at any depth and anything after the document.
+func decodeJSONSchema(data []byte, schema *jsonSchema) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(schema); err != nil {
+		if keyword, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return fmt.Errorf("unsupported keyword %s", keyword)
+		}
+		return err
+	}
+	return requireJSONEnd(decoder)
+}
+
+// decodeJSONValue decodes a single JSON document with numbers kept as
+// json.Number. Trailing input other than whitespace is an error.
+func decodeJSONValue(data string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if err := requireJSONEnd(decoder); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// requireJSONEnd checks that decoder has no input left after its value.
+func requireJSONEnd(decoder *json.Decoder) error {
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid character after top-level value at offset %d", decoder.InputOffset())
+	}
+	return nil
+}
+
+// resolveRulerPath resolves a path written in the ruler file. Relative paths
+// are taken relative to the directory of the ruler file when it is known.
+func resolveRulerPath(path string, metadata *Metadata) string {
+	if filepath.IsAbs(path) || metadata == nil || metadata.RulerDir == "" {
+		return path
+	}
+	return filepath.Join(metadata.RulerDir, path)
+}
+
+// compile checks type names and prepares const, patterns and
+// additionalProperties for the schema tree.
+func (s *jsonSchema) compile() error {
+	for _, schemaType := range s.Type {
+		if !slices.Contains(jsonSchemaTypeNames, schemaType) {
+			return fmt.Errorf("unsupported type [%s]", schemaType)
+		}
+	}
+	if len(s.Const) > 0 {
+		value, err := decodeJSONValue(string(s.Const))
+		if err != nil {
+			return err
+		}
+		s.constValue, s.hasConst = value, true
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = pattern
+	}
+
+	switch trimmed := bytes.TrimSpace(s.AdditionalProperties); {
+	case len(trimmed) == 0, bytes.Equal(trimmed, []byte("true")):
+	case bytes.Equal(trimmed, []byte("false")):
+		s.noAdditional = true
+	default:
+		s.additionalProps = &jsonSchema{}
+		if err := decodeJSONSchema(trimmed, s.additionalProps); err != nil {
+			return err
+		}
+		if err := s.additionalProps.compile(); err != nil {
+			return err
+		}
+	}
+
+	for _, property := range s.Properties {
+		if err := property.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// validate checks a decoded JSON value against the schema and returns the
+// first violation, prefixed with the JSON path where it occurred.
+func (s *jsonSchema) validate(value any, path string) error {
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return jsonTypeMatches(t, value) }) {
+		return fmt.Errorf("%s: expected type %v", path, []string(s.Type))
+	}
+	if s.hasConst && !jsonValuesEqual(s.constValue, value) {
+		return fmt.Errorf("%s: expected const %s", path, s.Const)
+	}
+	if s.Enum != nil && !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonValuesEqual(e, value) }) {
+		return fmt.Errorf("%s: value is not in enum %v", path, s.Enum)
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			return fmt.Errorf("%s: length [%d] is less than minLength [%d]", path, length, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fmt.Errorf("%s: length [%d] is greater than maxLength [%d]", path, length, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fmt.Errorf("%s: value does not match pattern [%s]", path, s.Pattern)
+		}
+	case json.Number:
+		if s.Minimum != nil && compareJSONNumbers(v, *s.Minimum) < 0 {
+			return fmt.Errorf("%s: value [%v] is less than minimum [%v]", path, v, *s.Minimum)
+		}
+		if s.Maximum != nil && compareJSONNumbers(v, *s.Maximum) > 0 {
+			return fmt.Errorf("%s: value [%v] is greater than maximum [%v]", path, v, *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && compareJSONNumbers(v, *s.ExclusiveMinimum) <= 0 {
+			return fmt.Errorf("%s: value [%v] is not greater than exclusiveMinimum [%v]", path, v, *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && compareJSONNumbers(v, *s.ExclusiveMaximum) >= 0 {
+			return fmt.Errorf("%s: value [%v] is not less than exclusiveMaximum [%v]", path, v, *s.ExclusiveMaximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Errorf("%s: [%d] items is less than minItems [%d]", path, len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fmt.Errorf("%s: [%d] items is greater than maxItems [%d]", path, len(v), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: required property [%s] is missing", path, name)
+			}
+		}
+		// Visit properties in sorted order so the first violation is deterministic.
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			propertyPath := path + "." + name
+			if property, ok := s.Properties[name]; ok {
+				if err := property.validate(v[name], propertyPath); err != nil {
+					return err
+				}
+				continue
+			}
+			if s.noAdditional {
+				return fmt.Errorf("%s: additional property is not allowed", propertyPath)
+			}
+			if s.additionalProps != nil {
+				if err := s.additionalProps.validate(v[name], propertyPath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonTypeMatches reports whether a value decoded by decodeJSONValue has the
+// given JSON Schema type.
+func jsonTypeMatches(schemaType string, value any) bool {
+	switch schemaType {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		v, ok := value.(json.Number)
+		return ok && jsonNumberRat(v).IsInt()
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	default:
+		return false
+	}
+}
+
+// jsonNumberRat returns the exact value of a JSON number. The decoder only
+// produces valid numbers, which big.Rat parses without loss.
+func jsonNumberRat(n json.Number) *big.Rat {
+	r, ok := new(big.Rat).SetString(string(n))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// compareJSONNumbers compares two JSON numbers by their exact values.
+func compareJSONNumbers(a, b json.Number) int {
+	return jsonNumberRat(a).Cmp(jsonNumberRat(b))
+}
+
+// jsonValuesEqual reports whether two decoded JSON values are equal, with
+// numbers compared by value (1, 1.0 and 1e0 are equal).
+func jsonValuesEqual(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		return ok && compareJSONNumbers(a, b) == 0
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, jsonValuesEqual)
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !jsonValuesEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,50 @@
+package csvons
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// RuleStats counts the work done by rules. Attach it to a context with
+// WithRuleStats and run rules with metadata.WithContext(ctx) to collect it.
+// Counters are updated atomically, so rules running concurrently may share
+// one RuleStats.
+type RuleStats struct {
+	Rows      atomic.Int64 // Data records read by field expressions and streamed passes, once per pass.
+	Values    atomic.Int64 // Values yielded by field expressions.
+	CacheHits atomic.Int64 // Repeated values exists and vtype skipped because they were already checked.
+}
+
+type ruleStatsKey struct{}
+
+// WithRuleStats returns a copy of ctx that carries stats.
+func WithRuleStats(ctx context.Context, stats *RuleStats) context.Context {
+	return context.WithValue(ctx, ruleStatsKey{}, stats)
+}
+
+// ruleStats returns the RuleStats carried by the metadata's context, or nil.
+func (m *Metadata) ruleStats() *RuleStats {
+	if m == nil || m.ctx == nil {
+		return nil
+	}
+	stats, _ := m.ctx.Value(ruleStatsKey{}).(*RuleStats)
+	return stats
+}
+
+// addPass records one pass over rows records yielding values values.
+// It is a no-op on a nil RuleStats.
+func (s *RuleStats) addPass(rows, values int) {
+	if s == nil {
+		return
+	}
+	s.Rows.Add(int64(rows))
+	s.Values.Add(int64(values))
+}
+
+// cacheHit records a value skipped because it was already checked.
+// It is a no-op on a nil RuleStats.
+func (s *RuleStats) cacheHit() {
+	if s != nil {
+		s.CacheHits.Add(1)
+	}
+}
@@ -0,0 +1,37 @@
+package csvons
+
+import (
+	"context"
+	"testing"
+)
+
+// TestRuleStatsCountsWork verifies the rows, values and cache hits counted
+// for exists and vtype, in memory and in streaming mode.
+func TestRuleStatsCountsWork(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "src", "Ref\n1;2\n2\n3\n")
+	writeTestCsv(t, dir, "dst", "ID\n1\n2\n3\n")
+
+	for _, streaming := range []bool{false, true} {
+		var stats RuleStats
+		metadata := testMetadata(dir)
+		metadata.Streaming = streaming
+		metadata = metadata.WithContext(WithRuleStats(context.Background(), &stats))
+
+		// The source has 3 rows and 4 values, one of them repeated; the
+		// destination index adds 3 rows and 3 values.
+		ExistsTest("src", []Exists{{DstFileStem: "dst", Fields: []FieldPair{{Src: "Ref[]", Dst: "ID"}}}}, metadata)
+		if rows, values, hits := stats.Rows.Load(), stats.Values.Load(), stats.CacheHits.Load(); rows != 6 || values != 7 || hits != 1 {
+			t.Fatalf("streaming=%v: exists counted rows=%d values=%d hits=%d", streaming, rows, values, hits)
+		}
+
+		stats = RuleStats{}
+		VTypeTest("src", []VType{{Field: "Ref[]", Type: "int"}}, metadata)
+		if rows, values, hits := stats.Rows.Load(), stats.Values.Load(), stats.CacheHits.Load(); rows != 3 || values != 4 || hits != 1 {
+			t.Fatalf("streaming=%v: vtype counted rows=%d values=%d hits=%d", streaming, rows, values, hits)
+		}
+	}
+
+	// Without RuleStats in the context nothing is counted, and nothing fails.
+	VTypeTest("src", []VType{{Field: "Ref[]", Type: "int"}}, testMetadata(dir))
+}
@@ -0,0 +1,185 @@
+package csvons
+
+import (
+	"encoding/csv"
+	"io"
+	"iter"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// csvStream reads a CSV file one record at a time. The slice returned by
+// next is reused by the following call.
+type csvStream struct {
+	path   string
+	file   *os.File
+	reader *csv.Reader
+	index  int // 0-based index of the next record.
+}
+
+// openCsvStream opens the CSV file identified by stem for streaming. Like
+// ReadCsvFile, every row must match the first row's cell count.
+func openCsvStream(stem string, metadata *Metadata) (*csvStream, error) {
+	path := filepath.Join(metadata.CSVFileFolder, stem+metadata.Extension)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+	return &csvStream{path: path, file: file, reader: reader}, nil
+}
+
+// next returns the next record and its 0-based index, or io.EOF at the end.
+func (s *csvStream) next() (int, []string, error) {
+	record, err := s.reader.Read()
+	if err != nil {
+		return 0, nil, err
+	}
+	s.index++
+	return s.index - 1, record, nil
+}
+
+// Close releases the file handle.
+func (s *csvStream) Close() error {
+	return s.file.Close()
+}
+
+// recordSource provides a rule with the header and data records of its
+// source file.
+//
+// Normally all records are read at once (through metadata.Tables). In
+// streaming mode (metadata.Streaming) only the header is kept and every pass
+// over the values re-reads the file record by record, so memory use does
+// not grow with the file; rules then hold only the per-column state they
+// need, such as the values already seen.
+type recordSource struct {
+	stem      string
+	metadata  *Metadata
+	table     *Table // Header only (no Records) when streaming.
+	streaming bool
+}
+
+// requiredRecordSource validates the metadata indices and opens the source
+// file of a rule. It aborts via failRuntime when the indices are invalid or
+// the file has no data rows.
+func requiredRecordSource(ctx ValidationContext, stem string, metadata *Metadata) *recordSource {
+	if metadata == nil || !metadata.Streaming {
+		return &recordSource{stem: stem, metadata: metadata, table: requiredSourceTable(ctx, stem, metadata, 0)}
+	}
+	return requiredStreamSource(ctx, stem, metadata)
+}
+
+// requiredStreamSource is like requiredRecordSource but always streams,
+// whatever metadata.Streaming says.
+func requiredStreamSource(ctx ValidationContext, stem string, metadata *Metadata) *recordSource {
+	if !requiredIndices(ctx, metadata) {
+		return nil
+	}
+	checkCancelled(ctx, metadata)
+
+	stream, err := openCsvStream(stem, metadata)
+	if err != nil {
+		failRuntime(ctx, "error opening file %s: %v", stem+metadata.Extension, err)
+		return nil
+	}
+	defer stream.Close()
+
+	// Read up to the first data record; only the header is kept.
+	source := &recordSource{stem: stem, metadata: metadata, streaming: true}
+	for stream.index <= metadata.DataIndex {
+		i, record, err := stream.next()
+		if err == io.EOF {
+			failRuntime(ctx, "src_records length [%d] <= data_index [%d]", stream.index, metadata.DataIndex)
+			return nil
+		}
+		if err != nil {
+			failRuntime(ctx, "error reading file %s: %v", stream.path, err)
+			return nil
+		}
+		if i == metadata.NameIndex {
+			source.table = headerTable(slices.Clone(record))
+		}
+	}
+	return source
+}
+
+// occurrences iterates the values of a field expression over the data
+// records, like requiredFieldOccurrences. A streamed pass opens the file when
+// iteration starts and closes it when iteration ends; read errors abort via
+// failRuntime.
+func (s *recordSource) occurrences(fieldExpr FieldExpr, fieldName string, ctx ValidationContext) iter.Seq[FieldOccurrence] {
+	if !s.streaming {
+		return requiredFieldOccurrences(s.metadata, fieldExpr, fieldName, s.table, ctx)
+	}
+
+	ctx.Field = fieldName
+	if fieldExpr == nil {
+		failRuntime(ctx, "field expression [%s] is nil", fieldName)
+		return nil
+	}
+	extractor, ok := fieldExpr.(recordExtractor)
+	if !ok {
+		failRuntime(ctx, "field expression [%s] cannot resolve values", fieldName)
+		return nil
+	}
+	extract := extractor.recordExtract(s.table)
+	if extract == nil {
+		failRuntime(ctx, "field expression [%s] cannot resolve values", fieldName)
+		return nil
+	}
+
+	return func(yield func(FieldOccurrence) bool) {
+		stream, err := openCsvStream(s.stem, s.metadata)
+		if err != nil {
+			failRuntime(ctx, "error opening file %s: %v", s.stem+s.metadata.Extension, err)
+			return
+		}
+		defer stream.Close()
+		log.Printf("streaming [%s] from %s", fieldName, stream.path)
+
+		// Values are substrings of the record's line; clone them so values
+		// kept by a rule do not pin whole lines in memory.
+		rows, values := 0, 0
+		defer func() { s.metadata.ruleStats().addPass(rows, values) }()
+
+		row := 0
+		emit := func(value string) bool {
+			values++
+			return yield(FieldOccurrence{Row: row, Value: strings.Clone(value)})
+		}
+		for {
+			i, record, err := stream.next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				failRuntime(ctx, "error reading file %s: %v", s.stem+s.metadata.Extension, err)
+				return
+			}
+			if i%cancelCheckInterval == 0 {
+				checkCancelled(ctx, s.metadata)
+			}
+			if i < s.metadata.DataIndex {
+				continue
+			}
+			rows++
+			row = i + 1
+			if !extract(i, record, emit) {
+				return
+			}
+		}
+	}
+}
+
+// rowValues maps each 1-based row number to the value a field expression
+// yields in that row, like requiredRowValues. The map holds one entry per row, so
+// even a streaming source needs memory proportional to its row count.
+func (s *recordSource) rowValues(fieldName string, ctx ValidationContext) map[int]string {
+	fieldExpr := GenerateFieldExpr(s.metadata, fieldName)
+	return collectRowValues(s.occurrences(fieldExpr, fieldName, ctx), fieldName, ctx)
+}
@@ -0,0 +1,144 @@
+package csvons
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStreamingRulers runs the exists, unique and vtype rules of the ruler
+// files used by their in-memory tests in streaming mode; they must pass
+// exactly as they do in memory.
+func TestStreamingRulers(t *testing.T) {
+	root := projectRoot()
+	for _, name := range []string{"ruler.json", "ruler_employees.json", "ruler_orders.json", "ruler_products.json"} {
+		configFileName := filepath.Join(root, "ruler", name)
+		rules, metadata := ReadConfigFile(configFileName)
+		if rules == nil || metadata == nil {
+			t.Fatalf("read config file error: file_name=%s", configFileName)
+		}
+		metadata.CSVFileFolder = filepath.Join(root, metadata.CSVFileFolder)
+		metadata.Streaming = true
+
+		for stem, v := range rules {
+			rulers := map[string]json.RawMessage{}
+			if err := json.Unmarshal(v, &rulers); err != nil {
+				t.Fatalf("error unmarshalling rulers for %s: %v", stem, err)
+			}
+
+			for k, v := range rulers {
+				switch k {
+				case "exists":
+					var exists []Exists
+					if err := json.Unmarshal(v, &exists); err != nil {
+						t.Fatalf("error unmarshalling exists: %v", err)
+					}
+					ExistsTest(stem, exists, metadata)
+				case "unique":
+					var unique Unique
+					if err := json.Unmarshal(v, &unique); err != nil {
+						t.Fatalf("error unmarshalling unique: %v", err)
+					}
+					UniqueTest(stem, &unique, metadata)
+				case "vtype":
+					var vtype []VType
+					if err := json.Unmarshal(v, &vtype); err != nil {
+						t.Fatalf("error unmarshalling vtype: %v", err)
+					}
+					VTypeTest(stem, vtype, metadata)
+				}
+			}
+		}
+	}
+}
+
+// TestStreamingReportsViolations verifies that streamed rules report the same
+// rows as in-memory ones.
+func TestStreamingReportsViolations(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "items", "ID,Group,Tags\n1,a,x;y\n2,a,z\n2,b,z;z\n")
+	writeTestCsv(t, dir, "groups", "Name\na\n")
+	metadata := testMetadata(dir)
+	metadata.Streaming = true
+
+	got := expectValidationError(t, func() {
+		UniqueTest("items", &Unique{Fields: []string{"ID"}}, metadata)
+	})
+	if got.Row == nil || *got.Row != 4 || got.Value != "2" {
+		t.Fatalf("unexpected unique error: %#v", got)
+	}
+
+	got = expectValidationError(t, func() {
+		UniqueTest("items", &Unique{Fields: []string{"Tags[]"}, Scope: "group", GroupBy: "Group"}, metadata)
+	})
+	if got.Row == nil || *got.Row != 4 || !strings.Contains(got.Message, "in group [b]") {
+		t.Fatalf("unexpected unique group error: %#v", got)
+	}
+
+	got = expectValidationError(t, func() {
+		ExistsTest("items", []Exists{{DstFileStem: "groups", Fields: []FieldPair{{Src: "Group", Dst: "Name"}}}}, metadata)
+	})
+	if got.Row == nil || *got.Row != 4 || got.Value != "b" {
+		t.Fatalf("unexpected exists error: %#v", got)
+	}
+
+	got = expectValidationError(t, func() {
+		VTypeTest("items", []VType{{Field: "Group", Type: "int"}}, metadata)
+	})
+	if got.Row == nil || *got.Row != 2 || got.Value != "a" {
+		t.Fatalf("unexpected vtype error: %#v", got)
+	}
+}
+
+// TestStreamingReportsReadErrors verifies that a malformed record after the
+// header aborts a streamed pass instead of ending it early.
+func TestStreamingReportsReadErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "ragged", "ID,Name\n1,a\n2\n")
+	metadata := testMetadata(dir)
+	metadata.Streaming = true
+
+	got := expectValidationError(t, func() {
+		VTypeTest("ragged", []VType{{Field: "ID", Type: "int"}}, metadata)
+	})
+	if got.ExitCode() != 2 || !strings.Contains(got.Message, "error reading file ragged.csv") {
+		t.Fatalf("unexpected read error: %#v", got)
+	}
+}
+
+// TestStreamingWholeFileRules verifies that in streaming mode single-pass
+// rules read their file from disk, while structure and table, which load
+// the whole file, share one cached parse.
+func TestStreamingWholeFileRules(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "items", "ID,Score\n1,5\n3,7\n2,6\n")
+	metadata := testMetadata(dir)
+	metadata.Tables = NewTableCache()
+	metadata.Streaming = true
+
+	got := expectValidationError(t, func() {
+		SortedTest("items", []Sorted{{Field: "ID", Type: "int"}}, metadata)
+	})
+	if got.Row == nil || *got.Row != 4 || got.Value != "2" {
+		t.Fatalf("unexpected sorted error: %#v", got)
+	}
+
+	maxRows := 3
+	TableTest("items", &TableRule{Rows: &CountRange{Max: &maxRows}}, metadata)
+	StructureTest("items", &Structure{}, metadata)
+	if err := os.Remove(filepath.Join(dir, "items.csv")); err != nil {
+		t.Fatalf("remove csv failed: %v", err)
+	}
+	TableTest("items", &TableRule{Rows: &CountRange{Max: &maxRows}}, metadata)
+	StructureTest("items", &Structure{}, metadata)
+
+	// Streamed rules do not use the cache and see the file is gone.
+	got = expectValidationError(t, func() {
+		SortedTest("items", []Sorted{{Field: "Score", Type: "int"}}, metadata)
+	})
+	if got.ExitCode() != 2 || !strings.Contains(got.Message, "error opening file items.csv") {
+		t.Fatalf("unexpected sorted error: %#v", got)
+	}
+}
@@ -0,0 +1,132 @@
+package csvons
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+)
+
+// Table is a parsed CSV file together with an O(1) lookup of its header.
+// Tables returned by a TableCache are shared between validators and must
+// be treated as read-only.
+type Table struct {
+	Fields  []string   // Header row (records[NameIndex]).
+	Records [][]string // All records, including the header and preamble rows.
+	columns map[string]int
+}
+
+// newTable builds a Table from records, using the row at nameIndex as header.
+// Records without a header row yield a Table with no Fields.
+func newTable(records [][]string, nameIndex int) *Table {
+	var fields []string
+	if nameIndex >= 0 && nameIndex < len(records) {
+		fields = records[nameIndex]
+	}
+	table := headerTable(fields)
+	table.Records = records
+	return table
+}
+
+// headerTable builds a Table with the given header and no records, for
+// resolving the columns of records that are held elsewhere or streamed.
+func headerTable(fields []string) *Table {
+	table := &Table{Fields: fields, columns: make(map[string]int, len(fields))}
+	for i, name := range fields {
+		// Keep the first column for duplicated header names, like slices.Index.
+		if _, ok := table.columns[name]; !ok {
+			table.columns[name] = i
+		}
+	}
+	return table
+}
+
+// Column returns the index of the header named name, or -1 if there is none.
+func (t *Table) Column(name string) int {
+	if i, ok := t.columns[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// TableCache parses each CSV file at most once per validation run and
+// shares the result between all validators. It is safe for concurrent use;
+// different files are loaded in parallel, the same file only once unless
+// its parse is cancelled.
+type TableCache struct {
+	mu     sync.Mutex
+	tables map[tableKey]*tableEntry
+}
+
+// tableKey identifies a parsed file. Strict and lenient (ragged) parses of
+// the same file are cached separately.
+type tableKey struct {
+	path            string
+	fieldsPerRecord int
+}
+
+// tableEntry is a parsed table, or one being parsed. done is closed once
+// the parse finishes; parsed stays false if it was cancelled.
+type tableEntry struct {
+	done   chan struct{}
+	table  *Table
+	parsed bool
+}
+
+// NewTableCache returns an empty cache.
+func NewTableCache() *TableCache {
+	return &TableCache{tables: make(map[tableKey]*tableEntry)}
+}
+
+// Load returns the parsed table for stem, reading the file on first use.
+// It returns nil when the file cannot be opened or parsed; failures are
+// cached as well, so a broken file is reported without being re-read.
+//
+// A parse aborted by its rule's cancellation is not cached: callers waiting
+// for it parse the file themselves. Waiting callers abort when their own
+// context is cancelled.
+func (c *TableCache) Load(stem string, metadata *Metadata, fieldsPerRecord int) *Table {
+	key := tableKey{path: filepath.Join(metadata.CSVFileFolder, stem+metadata.Extension), fieldsPerRecord: fieldsPerRecord}
+
+	for {
+		c.mu.Lock()
+		entry, ok := c.tables[key]
+		if !ok {
+			entry = &tableEntry{done: make(chan struct{})}
+			c.tables[key] = entry
+		}
+		c.mu.Unlock()
+
+		if !ok {
+			return c.parse(key, entry, stem, metadata, fieldsPerRecord)
+		}
+		select {
+		case <-entry.done:
+		case <-metadata.Context().Done():
+			checkCancelled(ValidationContext{File: csvFileName(stem, metadata)}, metadata)
+		}
+		if entry.parsed {
+			log.Printf("table [%s] loaded from cache", key.path)
+			return entry.table
+		}
+	}
+}
+
+// parse fills entry by parsing the file, or drops it from the cache if the
+// parse panics.
+func (c *TableCache) parse(key tableKey, entry *tableEntry, stem string, metadata *Metadata, fieldsPerRecord int) *Table {
+	defer func() {
+		if !entry.parsed {
+			c.mu.Lock()
+			delete(c.tables, key)
+			c.mu.Unlock()
+		}
+		close(entry.done)
+	}()
+
+	records := parseCsvFile(stem, metadata, fieldsPerRecord)
+	if records != nil {
+		entry.table = newTable(records, metadata.NameIndex)
+	}
+	entry.parsed = true
+	return entry.table
+}
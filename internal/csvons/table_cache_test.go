@@ -0,0 +1,61 @@
+package csvons
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTableCacheParsesOnce verifies that validators share one parse per file.
+func TestTableCacheParsesOnce(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "users", "ID,Name\n1,a\n2,b\n")
+	metadata := testMetadata(dir)
+	metadata.Tables = NewTableCache()
+
+	first := metadata.Tables.Load("users", metadata, 0)
+	if first == nil {
+		t.Fatalf("Load returned nil")
+	}
+
+	// Later validators read from the cache, not the disk.
+	if err := os.Remove(filepath.Join(dir, "users.csv")); err != nil {
+		t.Fatalf("remove csv failed: %v", err)
+	}
+	if second := metadata.Tables.Load("users", metadata, 0); second != first {
+		t.Fatalf("Load did not reuse the cached table")
+	}
+	UniqueTest("users", &Unique{Fields: []string{"ID"}}, metadata)
+	VTypeTest("users", []VType{{Field: "ID", Type: "int"}}, metadata)
+}
+
+// TestTableColumn verifies header lookup by name.
+func TestTableColumn(t *testing.T) {
+	table := newTable([][]string{{"ID", "Name", "ID"}, {"1", "a", "2"}}, 0)
+
+	if got := table.Column("Name"); got != 1 {
+		t.Errorf("Column(Name) = %d, expected 1", got)
+	}
+	if got := table.Column("ID"); got != 0 {
+		t.Errorf("Column(ID) = %d, expected 0", got)
+	}
+	if got := table.Column("Missing"); got != -1 {
+		t.Errorf("Column(Missing) = %d, expected -1", got)
+	}
+}
+
+// TestTableCacheSeparatesLenientParses verifies that a ragged file can still
+// be read leniently after a strict parse failed.
+func TestTableCacheSeparatesLenientParses(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "ragged", "ID,Name\n1\n")
+	metadata := testMetadata(dir)
+	metadata.Tables = NewTableCache()
+
+	if records := ReadCsvFile("ragged", metadata); records != nil {
+		t.Fatalf("strict read of ragged file should fail, got %q", records)
+	}
+	if records := readCsvRecords("ragged", metadata, -1); len(records) != 2 {
+		t.Fatalf("lenient read returned %q", records)
+	}
+}
@@ -1,22 +1,51 @@
 // Package csvons provides CSV constraint validation based on JSON configuration rules.
 //
-// It supports validating CSV files against three types of constraints:
+// It supports validating CSV files against the following constraints:
 //   - exists: values in a column must exist in another CSV file's column
-//   - unique: values in a column must be unique across all rows
-//   - vtype: values must conform to a specified type (int, float64, bool) and optional range
+//   - unique: values in a column must be unique across all rows, within a row, or within a group
+//   - vtype: values must conform to a specified type (int, float64, bool, string formats) and optional range
+//   - structure: every row must have the header's shape, with no blank rows
+//   - table: row counts and aggregates (sum, min, max, avg, count_distinct) over a column
+//   - sorted: values must appear in ascending or descending order
+//   - sequence: integer values must form a contiguous sequence
+//   - array_shape: array cells must have the expected element count and arity
+//   - map_keys: key sets of key=value map cells must satisfy required/forbidden/allowed keys
+//   - file_exists: values must name existing files in a local asset tree
+//   - i18n: every key must have complete, placeholder-consistent translations
+//   - outlier: numeric values far from their neighbours are reported as warnings
+//   - graph: self-referencing ID columns must form an acyclic, rooted, reachable graph
+//   - distribution: key:weight cells must have positive weights, a target sum and unique keys
+//   - lookup_compare: values must compare correctly with the joined row of another file
 //
 // Field expressions allow validation of values within nested data structures,
 // not just simple column values. See FieldExpr for supported expression types.
 package csvons
 
+import (
+	"context"
+	"encoding/json"
+)
+
 // ConstrainsConfig represents the complete configuration for CSV constraint validation.
-// It combines all constraint types (exists, unique, vtype) with the CSV metadata
+// It combines all constraint types (exists, unique, vtype, ...) with the CSV metadata
 // that describes how to read and interpret the CSV files.
 type ConstrainsConfig struct {
-	Exists   []Exists `json:"exists"`          // Rules for cross-file value existence validation.
-	Unique   Unique   `json:"unique"`          // Rules for column uniqueness validation.
-	VType    []VType  `json:"vtype"`           // Rules for value type and range validation.
-	Metadata Metadata `json:"csvons_metadata"` // Metadata describing CSV file structure.
+	Exists        []Exists        `json:"exists"`          // Rules for cross-file value existence validation.
+	Unique        Unique          `json:"unique"`          // Rules for column uniqueness validation.
+	VType         []VType         `json:"vtype"`           // Rules for value type and range validation.
+	Structure     *Structure      `json:"structure"`       // Rules for row shape and header integrity.
+	Table         *TableRule      `json:"table"`           // Rules for row counts and column aggregates.
+	Sorted        []Sorted        `json:"sorted"`          // Rules for value ordering.
+	Sequence      []Sequence      `json:"sequence"`        // Rules for contiguous integer sequences.
+	ArrayShape    []ArrayShape    `json:"array_shape"`     // Rules for per-row array cell shapes.
+	MapKeys       []MapKeys       `json:"map_keys"`        // Rules for key sets of map cells.
+	FileExists    []FileExists    `json:"file_exists"`     // Rules for asset path columns.
+	I18n          []I18n          `json:"i18n"`            // Rules for localization completeness.
+	Outlier       []Outlier       `json:"outlier"`         // Rules for statistical outlier warnings.
+	Graph         []Graph         `json:"graph"`           // Rules for parent/next reference graphs.
+	Distribution  []Distribution  `json:"distribution"`    // Rules for weighted-probability cells.
+	LookupCompare []LookupCompare `json:"lookup_compare"`  // Rules for cross-file joined comparisons.
+	Metadata      Metadata        `json:"csvons_metadata"` // Metadata describing CSV file structure.
 }
 
 // Metadata describes the structure and location of CSV files being validated.
@@ -41,51 +70,367 @@ type Metadata struct {
 	Lev1Separator  string `json:"lev1_separator"`  // Separator for first-level array values (e.g., ";").
 	Lev2Separator  string `json:"lev2_separator"`  // Separator for second-level nested values (e.g., ":").
 	FieldConnector string `json:"field_connector"` // Connector string for combining complex field values (e.g., "|").
+	MapSeparator   string `json:"map_separator"`   // Separator between key and value in map cells (default "=").
+	Streaming      bool   `json:"streaming"`       // Stream source files record by record instead of reading them whole (see recordSource).
+	RulerDir       string `json:"-"`               // Directory of the ruler file, set by ReadConfigFile; relative rule paths resolve against it.
+
+	Indexes *ValueIndexCache `json:"-"` // Destination column indexes shared by rules in one run; set by ReadConfigFile, nil disables sharing.
+	Tables  *TableCache      `json:"-"` // Parsed CSV files shared by rules in one run; set by ReadConfigFile, nil disables sharing.
+	Stems   []string         `json:"-"` // CSV file stems in ruler file order; set by ReadConfigFile.
+
+	ctx context.Context // Cancels rules run with this metadata; see WithContext.
 }
 
 // Exists defines a cross-file existence constraint.
 // It specifies that values in source columns must also exist in corresponding
 // columns of a destination CSV file.
 //
+// Strategy selects how destination values are looked up:
+//   - "hash" (default): an in-memory hash index of the destination column
+//   - "bloom": a Bloom filter, with candidate matches confirmed by rescanning the destination
+//   - "sorted": an external sort of both columns into temporary files, then a merge join
+//
+// "bloom" and "sorted" stream the destination file and stay within
+// MemoryLimitMB (default 64); "hash" fails as soon as its index grows past a
+// set limit, before building the rest of it.
+//
 // Example JSON:
 //
 //	{
 //	    "dst_file_stem": "username-d1",
-//	    "fields": [{"src": "Username", "dst": "Username"}]
+//	    "fields": [{"src": "Username", "dst": "Username"}],
+//	    "strategy": "bloom",
+//	    "memory_limit_mb": 256
 //	}
 type Exists struct {
-	DstFileStem string `json:"dst_file_stem"` // Base name (stem) of the target CSV file.
-	Fields      []struct {
-		Src string `json:"src"` // Field expression in the source file.
-		Dst string `json:"dst"` // Field expression in the destination file.
-	} `json:"fields"` // Pairs of source-destination field expressions to compare.
+	DstFileStem   string      `json:"dst_file_stem"`             // Base name (stem) of the target CSV file.
+	Fields        []FieldPair `json:"fields"`                    // Pairs of source-destination field expressions to compare.
+	Strategy      string      `json:"strategy,omitempty"`        // Lookup strategy: hash (default), bloom or sorted.
+	MemoryLimitMB int         `json:"memory_limit_mb,omitempty"` // Memory cap for the lookup, in MiB (optional).
+}
+
+// FieldPair pairs a field expression in the source file with one in a
+// destination file.
+type FieldPair struct {
+	Src string `json:"src"` // Field expression in the source file.
+	Dst string `json:"dst"` // Field expression in the destination file.
 }
 
 // Unique defines a column uniqueness constraint.
-// It specifies that all values within each listed field must be unique across all rows.
+// It specifies that all values within each listed field must be unique within a scope:
+//   - "table" (default): across all rows
+//   - "row": within each row only, e.g. the elements of "Tags[]" in one cell
+//   - "group": within each group of rows sharing the value of GroupBy
 //
 // Example JSON:
 //
 //	{"fields": ["Username", "marks{0}"]}
+//	{"fields": ["Tags[]"], "scope": "row"}
 type Unique struct {
-	Fields []string `json:"fields"` // Field expressions whose values must be unique.
+	Fields  []string `json:"fields"`             // Field expressions whose values must be unique.
+	Scope   string   `json:"scope,omitempty"`    // Uniqueness scope: "table", "row" or "group".
+	GroupBy string   `json:"group_by,omitempty"` // Field expression naming the group when Scope is "group".
 }
 
 // VType defines a value type and optional range constraint.
 // It validates that values in the specified field can be parsed as the given type,
 // and optionally fall within a numeric range.
 //
-// Supported types: "int", "float64", "bool".
+// Supported types: "int", "float64", "bool", and the string formats
+// "email", "url", "uuid", "color_hex", "semver", "ipv4", "ipv6",
+// "country_code", "currency_code" and "base64", plus "json" for cells holding
+// a JSON document, optionally checked against Schema or SchemaFile.
 // Range is only applicable to "int" and "float64" types.
 //
 // Example JSON:
 //
 //	{"field": "Age", "type": "int", "range": {"min": 1, "max": 100}}
 type VType struct {
-	Field string `json:"field"` // Field expression to validate.
-	Type  string `json:"type"`  // Expected value type: "int", "float64", or "bool".
-	Range *struct {
-		Min float64 `json:"min"` // Minimum allowed value (inclusive).
-		Max float64 `json:"max"` // Maximum allowed value (inclusive).
-	} `json:"range,omitempty"` // Optional numeric range constraint.
+	Field      string          `json:"field"`                 // Field expression to validate.
+	Type       string          `json:"type"`                  // Expected value type, e.g. "int", "float64", "bool" or "uuid".
+	Range      *Range          `json:"range,omitempty"`       // Optional numeric range constraint.
+	Schema     json.RawMessage `json:"schema,omitempty"`      // Optional inline JSON Schema for the "json" type.
+	SchemaFile string          `json:"schema_file,omitempty"` // Optional JSON Schema file for the "json" type, relative to the ruler.
+}
+
+// Range is an inclusive numeric interval shared by rules that bound values.
+type Range struct {
+	Min float64 `json:"min"` // Minimum allowed value (inclusive).
+	Max float64 `json:"max"` // Maximum allowed value (inclusive).
+}
+
+// Structure defines structural integrity checks for a whole CSV file.
+// Unlike the value-level rules, it inspects the shape of every record:
+// rows with too few or too many cells, fully blank rows, header names with
+// leading or trailing whitespace, and the rows between name_index and
+// data_index (e.g. type or comment rows).
+//
+// Example JSON:
+//
+//	{
+//	    "allow_blank_rows": false,
+//	    "preamble": [{"row": 1, "pattern": "^(int|string|float64)$"}]
+//	}
+type Structure struct {
+	AllowRagged           bool          `json:"allow_ragged"`            // Accept rows whose cell count differs from the header.
+	AllowBlankRows        bool          `json:"allow_blank_rows"`        // Accept data rows where every cell is blank.
+	AllowHeaderWhitespace bool          `json:"allow_header_whitespace"` // Accept header names with surrounding whitespace.
+	Preamble              []PreambleRow `json:"preamble,omitempty"`      // Expected shape of rows between name_index and data_index.
+}
+
+// PreambleRow describes the expected shape of one row located between
+// name_index and data_index.
+type PreambleRow struct {
+	Row     int      `json:"row"`               // Row index (0-based, same as name_index/data_index).
+	Values  []string `json:"values,omitempty"`  // Exact cell values expected in this row.
+	Pattern string   `json:"pattern,omitempty"` // Regular expression every cell must match.
+}
+
+// TableRule defines table-level constraints: the number of data rows and
+// aggregate values computed over whole columns.
+//
+// Example JSON:
+//
+//	{
+//	    "rows": {"min": 10, "max": 10},
+//	    "aggregates": [
+//	        {"field": "DropRate", "func": "sum", "group_by": "GroupID", "equals": 100, "tolerance": 0.001}
+//	    ]
+//	}
+type TableRule struct {
+	Rows       *CountRange `json:"rows,omitempty"`       // Optional bounds on the number of data rows.
+	Aggregates []Aggregate `json:"aggregates,omitempty"` // Aggregate constraints over field expressions.
+}
+
+// CountRange bounds a count, such as the number of data rows (rows from
+// data_index onwards) or the number of elements in an array cell.
+// Either bound may be omitted.
+type CountRange struct {
+	Min *int `json:"min,omitempty"` // Minimum count (inclusive).
+	Max *int `json:"max,omitempty"` // Maximum count (inclusive).
+}
+
+// Aggregate computes a single value over all values of a field expression,
+// optionally once per group, and checks it against an expected value or range.
+//
+// Supported functions: "sum", "min", "max", "avg" (numeric values) and
+// "count_distinct" (any values).
+type Aggregate struct {
+	Field     string   `json:"field"`               // Field expression to aggregate.
+	Func      string   `json:"func"`                // Aggregate function name.
+	GroupBy   string   `json:"group_by,omitempty"`  // Optional field expression whose value groups rows.
+	Equals    *float64 `json:"equals,omitempty"`    // Expected aggregate value.
+	Range     *Range   `json:"range,omitempty"`     // Allowed aggregate range (inclusive).
+	Tolerance float64  `json:"tolerance,omitempty"` // Absolute tolerance applied to equals and range.
+}
+
+// Sorted defines an ordering constraint over the values of a field expression,
+// taken in row order.
+//
+// Supported orders: "asc" (default) and "desc".
+// Supported types: "string" (default, byte-wise), "int" and "float64".
+//
+// Example JSON:
+//
+//	{"field": "ExpRequired", "order": "asc", "strict": true, "type": "int"}
+type Sorted struct {
+	Field  string `json:"field"`            // Field expression whose values must be ordered.
+	Order  string `json:"order,omitempty"`  // "asc" or "desc".
+	Strict bool   `json:"strict,omitempty"` // Reject equal neighbouring values.
+	Type   string `json:"type,omitempty"`   // Comparison type: "string", "int" or "float64".
+}
+
+// Sequence defines a contiguous integer sequence over the values of a field
+// expression, taken in row order: each value must equal the previous one plus Step.
+//
+// Example JSON:
+//
+//	{"field": "ID", "start": 1, "step": 1}
+type Sequence struct {
+	Field string `json:"field"`           // Field expression whose values form the sequence.
+	Start *int64 `json:"start,omitempty"` // Optional required first value.
+	Step  int64  `json:"step,omitempty"`  // Difference between neighbouring values (default 1).
+}
+
+// ArrayShape defines per-row shape constraints for an array-like cell
+// (lev1 elements, each optionally split into lev2 parts).
+//
+// An empty cell has zero elements.
+//
+// Example JSON:
+//
+//	{"field": "Items", "elements": {"min": 1}, "arity": 2, "same_length_as": ["Scores"]}
+type ArrayShape struct {
+	Field        string      `json:"field"`                    // Column name holding the array.
+	Elements     *CountRange `json:"elements,omitempty"`       // Bounds on the number of lev1 elements.
+	Arity        int         `json:"arity,omitempty"`          // Required number of lev2 parts per element (0 disables the check).
+	SameLengthAs []string    `json:"same_length_as,omitempty"` // Columns whose element count must match in the same row.
+}
+
+// MapKeys defines key set constraints for map cells such as "atk=10;def=5",
+// where entries are split by Lev1Separator and each key from its value by
+// MapSeparator.
+//
+// Example JSON:
+//
+//	{
+//	    "field": "Stats",
+//	    "required": ["atk"],
+//	    "forbidden": ["debug"],
+//	    "keys_from": {"dst_file_stem": "stat_names", "field": "Key"}
+//	}
+type MapKeys struct {
+	Field     string      `json:"field"`               // Column name holding the map.
+	Required  []string    `json:"required,omitempty"`  // Keys that must be present in every row.
+	Forbidden []string    `json:"forbidden,omitempty"` // Keys that must not be present.
+	Allowed   []string    `json:"allowed,omitempty"`   // If set, the only keys that may be present.
+	KeysFrom  *KeysSource `json:"keys_from,omitempty"` // If set, keys must exist in a column of another file.
+}
+
+// KeysSource names a column of another CSV file whose values form a key set.
+type KeysSource struct {
+	DstFileStem string `json:"dst_file_stem"` // Base name (stem) of the file holding the keys.
+	Field       string `json:"field"`         // Field expression in that file.
+}
+
+// FileExists defines a constraint that the values of a field expression name
+// existing files on the local filesystem.
+//
+// Each value is substituted into Template (default "{value}") and resolved
+// against BaseDir, which is relative to the ruler file. Every path component
+// must match with the same case unless CaseInsensitive is set, so that
+// assets resolve the same way on case-sensitive and case-insensitive systems.
+//
+// Example JSON:
+//
+//	{"field": "IconPath", "base_dir": "../assets", "template": "icons/{value}.png"}
+type FileExists struct {
+	Field           string   `json:"field"`                      // Field expression holding the paths.
+	BaseDir         string   `json:"base_dir,omitempty"`         // Directory the paths are relative to.
+	Template        string   `json:"template,omitempty"`         // Path template; "{value}" is replaced by the cell value.
+	Extensions      []string `json:"extensions,omitempty"`       // If set, allowed file extensions (e.g. ".png").
+	CaseInsensitive bool     `json:"case_insensitive,omitempty"` // Match path components ignoring case.
+	AllowEmpty      bool     `json:"allow_empty,omitempty"`      // Skip empty values instead of rejecting them.
+}
+
+// I18n defines a localization completeness constraint for a strings table.
+// The source file holds a key column and the source-language text; the
+// translations are either further columns of the same file (Locales) or
+// separate per-locale files joined on the key (Files).
+//
+// Example JSON:
+//
+//	{
+//	    "key": "Key",
+//	    "source": "en",
+//	    "locales": ["zh", "ja"],
+//	    "placeholders": true,
+//	    "max_length_ratio": 2.5
+//	}
+type I18n struct {
+	Key            string       `json:"key"`                        // Field expression holding the string key.
+	Source         string       `json:"source"`                     // Field expression holding the source text.
+	Locales        []string     `json:"locales,omitempty"`          // Columns of this file holding required translations.
+	Files          []LocaleFile `json:"files,omitempty"`            // Per-locale files holding required translations.
+	Placeholders   bool         `json:"placeholders,omitempty"`     // Require the same placeholders ({0}, {name}, %s) as the source.
+	MaxLengthRatio float64      `json:"max_length_ratio,omitempty"` // If set, max translation length as a multiple of the source length.
+}
+
+// LocaleFile names a per-locale strings file for the I18n rule.
+type LocaleFile struct {
+	Locale      string `json:"locale"`        // Locale name used in error messages.
+	DstFileStem string `json:"dst_file_stem"` // Base name (stem) of the locale file.
+	Key         string `json:"key"`           // Field expression holding the string key in the locale file.
+	Text        string `json:"text"`          // Field expression holding the translation in the locale file.
+}
+
+// Outlier defines a statistical outlier check over the numeric values of a
+// field expression. Outliers are reported as warnings, not failures.
+//
+// Supported methods (with the default Threshold):
+//   - "zscore" (3): |value - mean| / standard deviation exceeds Threshold
+//   - "iqr" (1.5): value lies outside [Q1 - Threshold*IQR, Q3 + Threshold*IQR]
+//   - "ratio" (10): value / previous value in row order exceeds Threshold or
+//     falls below 1/Threshold
+//
+// Example JSON:
+//
+//	{"field": "Damage", "method": "ratio", "threshold": 10}
+type Outlier struct {
+	Field     string  `json:"field"`               // Field expression with numeric values.
+	Method    string  `json:"method"`              // "zscore", "iqr" or "ratio".
+	Threshold float64 `json:"threshold,omitempty"` // Method threshold; 0 uses the method default.
+}
+
+// Graph defines constraints on a graph whose nodes are the rows of one file
+// and whose edges are references between rows, such as ParentID or NextID
+// columns pointing at the ID column of the same file.
+//
+// Each edge expression yields the referenced node IDs of a row; repeat
+// expressions such as "Prereqs[]" yield several edges per row and empty
+// values mean no edge. With EdgeType "parent" (default) a reference names
+// the row's parent; with "child" it names a successor.
+//
+// Example JSON:
+//
+//	{"node": "QuestID", "edges": ["ParentID"], "acyclic": true, "single_root": true, "max_depth": 5}
+type Graph struct {
+	Node       string   `json:"node"`                  // Field expression holding each row's node ID.
+	Edges      []string `json:"edges"`                 // Field expressions holding referenced node IDs.
+	EdgeType   string   `json:"edge_type,omitempty"`   // "parent" (default) or "child".
+	Acyclic    bool     `json:"acyclic,omitempty"`     // Reject cycles, reporting the cycle path.
+	SingleRoot bool     `json:"single_root,omitempty"` // Require exactly one node without a parent.
+	MaxDepth   int      `json:"max_depth,omitempty"`   // If set, max edges from a root to any node (requires acyclic).
+	Start      []string `json:"start,omitempty"`       // If set, every node must be reachable from these nodes.
+}
+
+// Distribution defines checks on weighted-probability cells such as drop
+// tables stored as "item:weight" pairs ("widget:5;gadget:3").
+//
+// Key and Weight are nested field expressions over the same column (e.g.
+// "Items{0}" and "Items{1}"), so each key is paired with the weight from the
+// same lev1 element. A distribution is the elements of one row (Scope "row",
+// default) or of all rows sharing the GroupBy value (Scope "group"). Within a
+// distribution every weight must be positive, a key may appear only once,
+// and the weights must add up to Sum when it is set.
+//
+// Example JSON:
+//
+//	{"key": "Items{0}", "weight": "Items{1}", "sum": 100, "tolerance": 0.001}
+type Distribution struct {
+	Key       string   `json:"key"`                 // Nested field expression selecting the item key.
+	Weight    string   `json:"weight"`              // Nested field expression selecting the weight.
+	Sum       *float64 `json:"sum,omitempty"`       // Optional required total weight per distribution.
+	Tolerance float64  `json:"tolerance,omitempty"` // Absolute tolerance applied to Sum.
+	Scope     string   `json:"scope,omitempty"`     // "row" (default) or "group".
+	GroupBy   string   `json:"group_by,omitempty"`  // Field expression naming the group when Scope is "group".
+}
+
+// LookupCompare defines a cross-file comparison: each source row is joined
+// to the destination row whose Keys values match, then the Compare pairs are
+// checked with typed operators.
+//
+// Supported operators: "==", "!=", "<", "<=", ">", ">=".
+// Supported types: "string" (default), "int" and "float64".
+//
+// Example JSON:
+//
+//	{
+//	    "dst_file_stem": "items",
+//	    "keys": [{"src": "ItemID", "dst": "ID"}],
+//	    "compare": [{"src": "Price", "op": ">=", "dst": "SellPrice", "type": "float64"}]
+//	}
+type LookupCompare struct {
+	DstFileStem  string       `json:"dst_file_stem"`           // Base name (stem) of the destination CSV file.
+	Keys         []FieldPair  `json:"keys"`                    // Field expressions joining source rows to destination rows.
+	Compare      []Comparison `json:"compare"`                 // Comparisons between joined rows.
+	AllowMissing bool         `json:"allow_missing,omitempty"` // Skip source rows without a matching destination row.
+}
+
+// Comparison compares a source field with a destination field of the joined row.
+type Comparison struct {
+	Src  string `json:"src"`            // Field expression in the source file (left operand).
+	Op   string `json:"op"`             // Comparison operator.
+	Dst  string `json:"dst"`            // Field expression in the destination file (right operand).
+	Type string `json:"type,omitempty"` // Comparison type: "string", "int" or "float64".
 }
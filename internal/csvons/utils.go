@@ -1,11 +1,15 @@
 package csvons
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 )
 
 // METADATA_KEY is the reserved key in the ruler JSON configuration file
@@ -13,9 +17,14 @@ import (
 // This key is extracted from the config and not treated as a CSV file stem.
 var METADATA_KEY = "csvons_metadata"
 
-// ReadConfigFile reads and parses a ruler JSON configuration file.
-// It extracts the metadata section (keyed by METADATA_KEY) and returns
-// the remaining keys as a map of CSV file stems to their raw JSON rule definitions.
+// ReadConfigFile reads and parses a ruler configuration file, in the format
+// implied by its extension (see ConfigFormatFromName and
+// ReadConfigFileFormat). It extracts the metadata section (keyed by
+// METADATA_KEY) and returns the remaining keys as a map of CSV file stems to
+// their raw JSON rule definitions.
+//
+// The stems are also recorded in metadata.Stems in the order they appear in
+// the file, so callers can iterate them deterministically.
 //
 // Returns (nil, nil) if the file cannot be read, parsed, or lacks valid metadata.
 //
@@ -25,12 +34,29 @@ var METADATA_KEY = "csvons_metadata"
 //	// rules["username"] → raw JSON containing exists/unique/vtype rules
 //	// metadata → parsed Metadata struct
 func ReadConfigFile(configFileName string) (map[string]json.RawMessage, *Metadata) {
+	return ReadConfigFileFormat(configFileName, "")
+}
+
+// ReadConfigFileFormat is like ReadConfigFile but reads the file in the
+// given format, one of ConfigFormats. YAML, TOML and JSONC files are
+// converted to JSON first, so rules are always returned as raw JSON. An
+// empty format is detected from the file name.
+func ReadConfigFileFormat(configFileName, format string) (map[string]json.RawMessage, *Metadata) {
+	if format == "" {
+		format = ConfigFormatFromName(configFileName)
+	}
+
 	// Read the entire config file into memory.
 	data, err := os.ReadFile(configFileName)
 	if err != nil {
 		log.Printf("error opening file %s: %v", configFileName, err)
 		return nil, nil
 	}
+	data, err = ConfigToJSON(data, format)
+	if err != nil {
+		log.Printf("error parsing %s file %s: %v", format, configFileName, err)
+		return nil, nil
+	}
 
 	// Parse the top-level JSON object into a map of raw messages.
 	// Each key is either a CSV file stem or the metadata key.
@@ -58,17 +84,71 @@ func ReadConfigFile(configFileName string) (map[string]json.RawMessage, *Metadat
 		return nil, nil
 	}
 
+	metadata.RulerDir = filepath.Dir(configFileName)
+	metadata.Stems, err = ObjectKeys(data)
+	if err != nil {
+		log.Printf("error reading keys of file %s: %v", configFileName, err)
+		return nil, nil
+	}
+	metadata.Stems = slices.DeleteFunc(metadata.Stems, func(key string) bool { return key == METADATA_KEY })
+	metadata.Indexes = NewValueIndexCache()
+	metadata.Tables = NewTableCache()
+
 	// Remove the metadata key so only CSV file stem rules remain.
 	delete(cfg, METADATA_KEY)
 	return cfg, metadata
 }
 
+// ObjectKeys returns the keys of a JSON object in document order, which
+// encoding/json does not preserve when decoding into a map. A key that
+// appears more than once is listed at its first position.
+//
+// Example:
+//
+//	keys, _ := ObjectKeys([]byte(`{"b": 1, "a": 2}`))
+//	// keys → ["b", "a"]
+func ObjectKeys(data []byte) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected a JSON object, got %v", token)
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an object key, got %v", token)
+		}
+		// Skip the value; only the key order is needed.
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
 // ReadCsvFile reads a CSV file identified by its stem (base name) and metadata.
 // It constructs the full file path from the metadata's CSVFileFolder and Extension fields,
 // then reads all records from the CSV file.
 //
-// Note: Files are not cached; each call reads the file from disk.
-// Callers should ensure each file is read only once for performance.
+// When metadata.Tables is set (ReadConfigFile sets it), each file is parsed
+// once per run and the same records are returned to every caller; the
+// records must then be treated as read-only. Without a cache each call reads
+// the file from disk.
 //
 // Returns nil if metadata is nil or the file cannot be opened/parsed.
 //
@@ -78,11 +158,39 @@ func ReadConfigFile(configFileName string) (map[string]json.RawMessage, *Metadat
 //	// records[0] → header row
 //	// records[1:] → data rows
 func ReadCsvFile(stem string, metadata *Metadata) [][]string {
+	return readCsvRecords(stem, metadata, 0)
+}
+
+// readCsvRecords reads a CSV file like ReadCsvFile, passing fieldsPerRecord
+// through to csv.Reader. A value of 0 requires every row to match the first
+// row's cell count; a negative value accepts ragged rows.
+func readCsvRecords(stem string, metadata *Metadata, fieldsPerRecord int) [][]string {
+	if table := readCsvTable(stem, metadata, fieldsPerRecord); table != nil {
+		return table.Records
+	}
+	return nil
+}
+
+// readCsvTable reads a CSV file like readCsvRecords and returns it as a
+// Table, or nil if it cannot be read.
+func readCsvTable(stem string, metadata *Metadata, fieldsPerRecord int) *Table {
 	if metadata == nil {
 		log.Println("metadata is nil")
 		return nil
 	}
+	if metadata.Tables != nil {
+		return metadata.Tables.Load(stem, metadata, fieldsPerRecord)
+	}
+	records := parseCsvFile(stem, metadata, fieldsPerRecord)
+	if records == nil {
+		return nil
+	}
+	return newTable(records, metadata.NameIndex)
+}
 
+// parseCsvFile reads and parses a CSV file from disk without caching. It
+// aborts via failRuntime when the metadata's context is cancelled.
+func parseCsvFile(stem string, metadata *Metadata, fieldsPerRecord int) [][]string {
 	// Build the full file path: <folder>/<stem><extension>
 	fullPath := filepath.Join(metadata.CSVFileFolder, stem+metadata.Extension)
 	csvFile, err := os.Open(fullPath)
@@ -94,11 +202,70 @@ func ReadCsvFile(stem string, metadata *Metadata) [][]string {
 
 	// Parse the entire CSV file into a 2D string slice.
 	csvReader := csv.NewReader(csvFile)
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		log.Printf("error reading file %s: %v", fullPath, err)
+	csvReader.FieldsPerRecord = fieldsPerRecord
+	var records [][]string
+	for {
+		if len(records)%cancelCheckInterval == 0 {
+			checkCancelled(ValidationContext{File: csvFileName(stem, metadata)}, metadata)
+		}
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			log.Printf("error reading file %s: %v", fullPath, err)
+			return nil
+		}
+		records = append(records, record)
+	}
+}
+
+// requiredSourceTable validates the metadata indices and reads the source
+// CSV file for a rule, returning it as a Table (shared through
+// metadata.Tables when there is a cache). It aborts via failRuntime when the
+// indices are invalid, the run is cancelled or the file has no data rows.
+//
+// Rules that need whole records, such as structure and table, read their
+// file this way even in streaming mode; a warning is logged then.
+func requiredSourceTable(ctx ValidationContext, stem string, metadata *Metadata, fieldsPerRecord int) *Table {
+	if !requiredIndices(ctx, metadata) {
+		return nil
+	}
+	checkCancelled(ctx, metadata)
+	if metadata.Streaming {
+		log.Printf("warning: rule [%s] loads %s whole; streaming does not apply to it", ctx.Rule, csvFileName(stem, metadata))
+	}
+
+	table := readCsvTable(stem, metadata, fieldsPerRecord)
+	srcLen := 0
+	if table != nil {
+		srcLen = len(table.Records)
+	}
+	if srcLen <= metadata.DataIndex {
+		failRuntime(ctx, "src_records length [%d] <= data_index [%d]", srcLen, metadata.DataIndex)
 		return nil
 	}
+	return table
+}
+
+// requiredIndices validates the metadata's name_index and data_index,
+// aborting via failRuntime when they are invalid.
+func requiredIndices(ctx ValidationContext, metadata *Metadata) bool {
+	if metadata == nil {
+		failRuntime(ctx, "metadata is nil")
+		return false
+	}
 
-	return records
+	nameIndex := metadata.NameIndex
+	if nameIndex < 0 {
+		failRuntime(ctx, "name_index [%d] is less than 0", nameIndex)
+		return false
+	}
+
+	dataIndex := metadata.DataIndex
+	if dataIndex <= nameIndex {
+		failRuntime(ctx, "data_index [%d] is less than or equal to name_index [%d]", dataIndex, nameIndex)
+		return false
+	}
+	return true
 }
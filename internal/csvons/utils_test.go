@@ -0,0 +1,45 @@
+package csvons
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestObjectKeys verifies that object keys are returned in document order.
+func TestObjectKeys(t *testing.T) {
+	keys, err := ObjectKeys([]byte(`{"zeta": {"a": 1}, "alpha": [1, 2], "mid": "x", "alpha": null}`))
+	if err != nil {
+		t.Fatalf("ObjectKeys failed: %v", err)
+	}
+	if expected := []string{"zeta", "alpha", "mid"}; !slices.Equal(keys, expected) {
+		t.Fatalf("ObjectKeys = %q, expected %q", keys, expected)
+	}
+
+	if _, err := ObjectKeys([]byte(`[1, 2]`)); err == nil {
+		t.Fatalf("ObjectKeys accepted a JSON array")
+	}
+}
+
+// TestReadConfigFileStemOrder verifies that metadata.Stems follows the ruler
+// file, without the metadata key.
+func TestReadConfigFileStemOrder(t *testing.T) {
+	configFileName := filepath.Join(t.TempDir(), "ruler.json")
+	config := `{
+		"weapons": {"unique": {"fields": ["ID"]}},
+		"csvons_metadata": {"csv_file_folder": ".", "name_index": 0, "data_index": 1, "extension": ".csv"},
+		"armors": {"unique": {"fields": ["ID"]}}
+	}`
+	if err := os.WriteFile(configFileName, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config failed: %v", err)
+	}
+
+	rules, metadata := ReadConfigFile(configFileName)
+	if rules == nil || metadata == nil {
+		t.Fatalf("read config file error: file_name=%s", configFileName)
+	}
+	if expected := []string{"weapons", "armors"}; !slices.Equal(metadata.Stems, expected) {
+		t.Fatalf("metadata.Stems = %q, expected %q", metadata.Stems, expected)
+	}
+}
@@ -0,0 +1,50 @@
+package csvons
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestCsv writes content to <dir>/<stem>.csv for ad-hoc validator tests.
+func writeTestCsv(t *testing.T, dir, stem, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, stem+".csv"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+}
+
+// testMetadata returns metadata pointing at dir with the default ruler layout.
+func testMetadata(dir string) *Metadata {
+	return &Metadata{
+		CSVFileFolder:  dir,
+		NameIndex:      0,
+		DataIndex:      1,
+		Extension:      ".csv",
+		Lev1Separator:  ";",
+		Lev2Separator:  ":",
+		FieldConnector: "|",
+	}
+}
+
+// expectValidationError runs fn and returns the ValidationError it panics with.
+// The test fails if fn returns normally or panics with another value.
+func expectValidationError(t *testing.T, fn func()) (got ValidationError) {
+	t.Helper()
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("expected validation panic")
+		}
+		err, ok := r.(ValidationError)
+		if !ok {
+			t.Fatalf("unexpected panic value: %#v", r)
+		}
+		got = err
+	}()
+
+	fn()
+	return got
+}
@@ -0,0 +1,177 @@
+package csvons
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+)
+
+// valueIndexEntryOverhead approximates the per-entry cost of a Go map with
+// string keys and int values (string header, value and bucket bookkeeping),
+// on top of the key bytes themselves.
+const valueIndexEntryOverhead = 40
+
+// valueIndex maps each distinct value yielded by a field expression over one
+// file to the first 1-based row it appears in.
+type valueIndex struct {
+	rows  map[string]int
+	bytes int64 // Estimated memory held by rows.
+}
+
+// lookup returns the first row holding value, if any.
+func (v *valueIndex) lookup(value string) (int, bool) {
+	row, ok := v.rows[value]
+	return row, ok
+}
+
+// ValueIndexCache shares destination column indexes across rules and stems
+// within one validation run, so a file referenced by many exists rules is
+// read and indexed once per field expression. It is safe for concurrent use;
+// different columns are indexed in parallel, the same column only once.
+//
+// The cache keeps an estimate of the memory held by all indexes; see Bytes.
+type ValueIndexCache struct {
+	mu      sync.Mutex
+	indexes map[string]*valueIndexEntry
+	bytes   int64
+	hits    int
+	builds  int
+}
+
+// valueIndexEntry is a cached index, or one being built. done is closed
+// once the build finishes; index stays nil if it failed.
+type valueIndexEntry struct {
+	done  chan struct{}
+	index *valueIndex
+}
+
+// NewValueIndexCache returns an empty cache.
+func NewValueIndexCache() *ValueIndexCache {
+	return &ValueIndexCache{indexes: make(map[string]*valueIndexEntry)}
+}
+
+// Bytes returns the estimated memory held by all cached indexes.
+func (c *ValueIndexCache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// Stats returns the number of lookups served from the cache and the number
+// of indexes built.
+func (c *ValueIndexCache) Stats() (hits, builds int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.builds
+}
+
+// index returns the cached index for key, calling build on a miss. The
+// cache lock is only held to find or add the key's entry: callers asking
+// for the same key wait for the first one's build, others build their own
+// columns meanwhile. A build that fails (panics) is not cached; a waiting
+// caller then builds the index itself, so the failure is reported in its
+// own rule's context. A waiting caller aborts via failRuntime if the
+// metadata's context is cancelled before the build finishes.
+func (c *ValueIndexCache) index(key string, metadata *Metadata, ctx ValidationContext, build func() *valueIndex) *valueIndex {
+	for {
+		c.mu.Lock()
+		entry, ok := c.indexes[key]
+		if !ok {
+			entry = &valueIndexEntry{done: make(chan struct{})}
+			c.indexes[key] = entry
+		}
+		c.mu.Unlock()
+
+		if !ok {
+			return c.build(key, entry, build)
+		}
+		select {
+		case <-entry.done:
+		case <-metadata.Context().Done():
+			checkCancelled(ctx, metadata)
+		}
+		if entry.index != nil {
+			c.mu.Lock()
+			c.hits++
+			c.mu.Unlock()
+			return entry.index
+		}
+	}
+}
+
+// build fills entry by calling build, or drops it from the cache if build
+// panics.
+func (c *ValueIndexCache) build(key string, entry *valueIndexEntry, build func() *valueIndex) *valueIndex {
+	defer func() {
+		if entry.index == nil {
+			c.mu.Lock()
+			delete(c.indexes, key)
+			c.mu.Unlock()
+		}
+		close(entry.done)
+	}()
+
+	index := build()
+	c.mu.Lock()
+	entry.index = index
+	c.bytes += index.bytes
+	c.builds++
+	total := c.bytes
+	c.mu.Unlock()
+	log.Printf("indexed [%s]: %d values, ~%d bytes (cache total ~%d bytes)", key, len(index.rows), index.bytes, total)
+	return index
+}
+
+// valueIndexKey identifies a field expression over a file under the metadata
+// settings that affect which values the expression yields.
+func valueIndexKey(stem, fieldName string, metadata *Metadata) string {
+	path := filepath.Join(metadata.CSVFileFolder, stem+metadata.Extension)
+	return fmt.Sprintf("%s#%s#%d#%q#%q#%q#%q", path, fieldName, metadata.DataIndex,
+		metadata.Lev1Separator, metadata.Lev2Separator, metadata.FieldConnector, metadata.MapSeparator)
+}
+
+// buildValueIndex indexes every value of a field expression. With a limit
+// (in bytes) above 0 it aborts via failRuntime as soon as the index grows
+// past it, so an oversized index is never completed or cached.
+func buildValueIndex(metadata *Metadata, fieldName string, source *recordSource, limit int64, ctx ValidationContext) *valueIndex {
+	fieldExpr := GenerateFieldExpr(metadata, fieldName)
+	index := &valueIndex{rows: make(map[string]int)}
+	for occurrence := range source.occurrences(fieldExpr, fieldName, ctx) {
+		if _, ok := index.rows[occurrence.Value]; ok {
+			continue
+		}
+		index.rows[occurrence.Value] = occurrence.Row
+		index.bytes += int64(len(occurrence.Value)) + valueIndexEntryOverhead
+		if limit > 0 && index.bytes > limit {
+			ctx.Row = rowPointer(occurrence.Row)
+			failRuntime(ctx, "dst_field [%s] index passes memory_limit_mb [%d] at row [%d]; use strategy bloom or sorted", fieldName, limit>>20, occurrence.Row)
+			return nil
+		}
+	}
+	return index
+}
+
+// requiredValueIndex returns the index of a field expression over the
+// destination file, from the metadata's shared cache when there is one.
+// loadSource is only called when the index has to be built. A limit above 0
+// caps the index size in bytes; it aborts via failRuntime when the index is,
+// or would grow, larger.
+func requiredValueIndex(metadata *Metadata, stem, fieldName string, limit int64, loadSource func() *recordSource, ctx ValidationContext) *valueIndex {
+	build := func() *valueIndex {
+		return buildValueIndex(metadata, fieldName, loadSource(), limit, ctx)
+	}
+	var index *valueIndex
+	if metadata.Indexes == nil {
+		index = build()
+	} else {
+		index = metadata.Indexes.index(valueIndexKey(stem, fieldName, metadata), metadata, ctx, build)
+	}
+
+	// An index built without a limit by another rule may still be too large.
+	if limit > 0 && index.bytes > limit {
+		failRuntime(ctx, "dst_field [%s] index needs ~%d bytes, over memory_limit_mb [%d]; use strategy bloom or sorted", fieldName, index.bytes, limit>>20)
+		return nil
+	}
+	return index
+}
@@ -0,0 +1,143 @@
+package csvons
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestValueIndexCacheSharedAcrossStems verifies that a destination column is
+// indexed once and reused by exists rules of other stems.
+func TestValueIndexCacheSharedAcrossStems(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "items", "ID,Name\na,x\nb,y\n")
+	writeTestCsv(t, dir, "shop", "ItemID\na\nb\n")
+	writeTestCsv(t, dir, "loot", "ItemID\nb\n")
+	metadata := testMetadata(dir)
+	metadata.Indexes = NewValueIndexCache()
+
+	rule := []Exists{{DstFileStem: "items", Fields: []FieldPair{{Src: "ItemID", Dst: "ID"}}}}
+	ExistsTest("shop", rule, metadata)
+
+	// The destination is not read again once its column is indexed.
+	if err := os.Remove(filepath.Join(dir, "items.csv")); err != nil {
+		t.Fatalf("remove csv failed: %v", err)
+	}
+	ExistsTest("loot", rule, metadata)
+
+	hits, builds := metadata.Indexes.Stats()
+	if hits != 1 || builds != 1 {
+		t.Fatalf("unexpected cache stats: hits=%d builds=%d", hits, builds)
+	}
+	if want := int64(2 * (1 + valueIndexEntryOverhead)); metadata.Indexes.Bytes() != want {
+		t.Fatalf("unexpected cache bytes: got %d want %d", metadata.Indexes.Bytes(), want)
+	}
+}
+
+// TestExistsWithoutIndexCache verifies lookups when no shared cache is configured.
+func TestExistsWithoutIndexCache(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "items", "ID\na\n")
+	writeTestCsv(t, dir, "shop", "ItemID\na\nz\n")
+
+	err := expectValidationError(t, func() {
+		ExistsTest("shop", []Exists{{DstFileStem: "items", Fields: []FieldPair{{Src: "ItemID", Dst: "ID"}}}}, testMetadata(dir))
+	})
+	if err.Row == nil || *err.Row != 3 || err.Value != "z" {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+// TestValueIndexCacheBuildsKeysConcurrently verifies that building one
+// column does not block building another, while callers of the same column
+// share a single build.
+func TestValueIndexCacheBuildsKeysConcurrently(t *testing.T) {
+	cache := NewValueIndexCache()
+	bStarted := make(chan struct{})
+	var builds atomic.Int32
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		cache.index("a", nil, ValidationContext{}, func() *valueIndex {
+			builds.Add(1)
+			select {
+			case <-bStarted:
+			case <-time.After(5 * time.Second):
+				t.Errorf("building b waited for building a")
+			}
+			return &valueIndex{rows: map[string]int{"x": 2}}
+		})
+	}()
+	for range 2 {
+		go func() {
+			defer wg.Done()
+			cache.index("b", nil, ValidationContext{}, func() *valueIndex {
+				builds.Add(1)
+				close(bStarted)
+				return &valueIndex{rows: map[string]int{"y": 2}}
+			})
+		}()
+	}
+	wg.Wait()
+
+	if builds.Load() != 2 {
+		t.Fatalf("unexpected build count: %d", builds.Load())
+	}
+	if hits, builds := cache.Stats(); hits != 1 || builds != 2 {
+		t.Fatalf("unexpected cache stats: hits=%d builds=%d", hits, builds)
+	}
+}
+
+// TestValueIndexCacheRetriesFailedBuild verifies that a build that panics
+// is not cached, so the next caller builds the index itself.
+func TestValueIndexCacheRetriesFailedBuild(t *testing.T) {
+	cache := NewValueIndexCache()
+	expectValidationError(t, func() {
+		cache.index("a", nil, ValidationContext{}, func() *valueIndex {
+			failValidation(ValidationContext{Rule: "exists"}, "dst file is missing")
+			return nil
+		})
+	})
+
+	index := cache.index("a", nil, ValidationContext{}, func() *valueIndex { return &valueIndex{rows: map[string]int{"x": 2}} })
+	if _, ok := index.lookup("x"); !ok {
+		t.Fatalf("index was not rebuilt")
+	}
+	if hits, builds := cache.Stats(); hits != 0 || builds != 1 {
+		t.Fatalf("unexpected cache stats: hits=%d builds=%d", hits, builds)
+	}
+}
+
+// TestValueIndexCacheWaiterStopsOnCancel verifies that a caller waiting for
+// another rule's build aborts when its own context is cancelled.
+func TestValueIndexCacheWaiterStopsOnCancel(t *testing.T) {
+	cache := NewValueIndexCache()
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	go cache.index("a", nil, ValidationContext{}, func() *valueIndex {
+		close(started)
+		<-release
+		return &valueIndex{rows: map[string]int{}}
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	metadata := testMetadata(t.TempDir()).WithContext(ctx)
+	err := expectValidationError(t, func() {
+		cache.index("a", metadata, ValidationContext{Rule: "exists"}, func() *valueIndex {
+			t.Errorf("waiter built the index")
+			return nil
+		})
+	})
+	if !errors.Is(err, ErrCancelled) || err.Rule != "exists" {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package csvons
+
+import (
+	"encoding/base64"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// formatValidators maps built-in vtype string formats to their validators.
+// Each validator reports whether the whole cell value matches the format.
+var formatValidators = map[string]func(string) bool{
+	"email":         isEmail,
+	"url":           isURL,
+	"uuid":          uuidPattern.MatchString,
+	"color_hex":     colorHexPattern.MatchString,
+	"semver":        semverPattern.MatchString,
+	"ipv4":          isIPv4,
+	"ipv6":          isIPv6,
+	"country_code":  func(v string) bool { return isoCodeListed(countryCodes, v) },
+	"currency_code": func(v string) bool { return isoCodeListed(currencyCodes, v) },
+	"base64":        isBase64,
+}
+
+var (
+	// uuidPattern matches the canonical 8-4-4-4-12 hexadecimal UUID form.
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	// colorHexPattern matches "#RGB", "#RGBA", "#RRGGBB" and "#RRGGBBAA".
+	colorHexPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+	// semverPattern is the regular expression recommended by semver.org.
+	semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+)
+
+// isEmail accepts a bare address such as "a@example.com";
+// display names ("Name <a@example.com>") are rejected.
+func isEmail(v string) bool {
+	addr, err := mail.ParseAddress(v)
+	return err == nil && addr.Address == v
+}
+
+// isURL accepts absolute URLs with a scheme and host.
+func isURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func isIPv4(v string) bool {
+	addr, err := netip.ParseAddr(v)
+	return err == nil && addr.Is4()
+}
+
+func isIPv6(v string) bool {
+	addr, err := netip.ParseAddr(v)
+	return err == nil && addr.Is6()
+}
+
+// isBase64 accepts padded standard base64 (RFC 4648 section 4).
+func isBase64(v string) bool {
+	_, err := base64.StdEncoding.Strict().DecodeString(v)
+	return err == nil
+}
+
+// isoCodeListed reports whether v is an upper-case code in the
+// space-separated code list.
+func isoCodeListed(codes, v string) bool {
+	if v == "" || strings.ContainsRune(v, ' ') {
+		return false
+	}
+	return strings.Contains(" "+codes+" ", " "+v+" ")
+}
+
+// countryCodes lists the ISO 3166-1 alpha-2 country codes.
+const countryCodes = "AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ " +
+	"BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ " +
+	"CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ " +
+	"DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR " +
+	"GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY " +
+	"HK HM HN HR HT HU ID IE IL IM IN IO IQ IR IS IT JE JM JO JP " +
+	"KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY " +
+	"MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ " +
+	"NA NC NE NF NG NI NL NO NP NR NU NZ OM " +
+	"PA PE PF PG PH PK PL PM PN PR PS PT PW PY QA RE RO RS RU RW " +
+	"SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ " +
+	"TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ " +
+	"UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW"
+
+// currencyCodes lists the active ISO 4217 currency codes.
+const currencyCodes = "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN " +
+	"BAM BBD BDT BGN BHD BIF BMD BND BOB BOV BRL BSD BTN BWP BYN BZD " +
+	"CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUP CVE CZK " +
+	"DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD " +
+	"HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY " +
+	"KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD " +
+	"MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN " +
+	"NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR " +
+	"RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL " +
+	"THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI UYU UYW UZS " +
+	"VED VES VND VUV WST XAF XAG XAU XBA XBB XBC XBD XCD XCG XDR XOF XPD XPF XPT XSU XTS XUA XXX " +
+	"YER ZAR ZMW ZWG"
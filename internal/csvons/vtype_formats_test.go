@@ -0,0 +1,57 @@
+package csvons
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFormatValidators uses table-driven tests to verify each built-in string format.
+func TestFormatValidators(t *testing.T) {
+	tests := []struct {
+		format  string
+		valid   []string
+		invalid []string
+	}{
+		{"email", []string{"a@example.com"}, []string{"a@", "Name <a@example.com>", ""}},
+		{"url", []string{"https://example.com/a?b=1"}, []string{"example.com", "/relative"}},
+		{"uuid", []string{"123e4567-e89b-12d3-a456-426614174000"}, []string{"123e4567e89b12d3a456426614174000"}},
+		{"color_hex", []string{"#fff", "#FFAA00", "#ffaa0080"}, []string{"fff", "#ffff0", "#ggg"}},
+		{"semver", []string{"1.0.0", "2.1.3-rc.1+build.5"}, []string{"1.0", "01.0.0"}},
+		{"ipv4", []string{"192.168.0.1"}, []string{"256.0.0.1", "::1"}},
+		{"ipv6", []string{"::1", "2001:db8::1"}, []string{"192.168.0.1"}},
+		{"country_code", []string{"US", "JP"}, []string{"us", "XX", "USA"}},
+		{"currency_code", []string{"USD", "EUR"}, []string{"usd", "ABC", "US"}},
+		{"base64", []string{"aGVsbG8=", ""}, []string{"aGVsbG8", "not base64!"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			validate := formatValidators[tt.format]
+			if validate == nil {
+				t.Fatalf("formatValidators missing format: %s", tt.format)
+			}
+			for _, v := range tt.valid {
+				if !validate(v) {
+					t.Errorf("%s(%q) = false, expected true", tt.format, v)
+				}
+			}
+			for _, v := range tt.invalid {
+				if validate(v) {
+					t.Errorf("%s(%q) = true, expected false", tt.format, v)
+				}
+			}
+		})
+	}
+}
+
+// TestVTypeFormatRejects verifies that format types report through VTypeTest.
+func TestVTypeFormatRejects(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCsv(t, dir, "skins", "Color\n#ff0000\n#ff0000\nred\n")
+	metadata := testMetadata(dir)
+
+	err := expectValidationError(t, func() { VTypeTest("skins", []VType{{Field: "Color", Type: "color_hex"}}, metadata) })
+	if err.Row == nil || *err.Row != 4 || !strings.Contains(err.Message, "is not a valid color_hex") {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}